@@ -1,21 +1,315 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"currency-converter-service/pkg/api"
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/config"
 	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/feed"
+	"currency-converter-service/pkg/grpcapi"
+	"currency-converter-service/pkg/metrics"
+	"currency-converter-service/pkg/store"
+
+	"github.com/shopspring/decimal"
 )
 
 func main() {
-	// Create currency converter instance
-	conv := converter.NewCurrencyConverter()
+	// Logs are JSON lines on stdout; the log package is routed through slog so
+	// startup messages share the format of the access log
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	// Rate storage backend: RATE_STORE=memory (default), file or sqlite.
+	// RATE_STORE_PATH selects the file, e.g. rates.json, rates.yaml or rates.db
+	provider, err := store.Open(os.Getenv("RATE_STORE"), os.Getenv("RATE_STORE_PATH"))
+	if err != nil {
+		log.Fatal("Failed to open rate store: ", err)
+	}
+	if closer, ok := provider.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	opts := []converter.Option{converter.WithRateProvider(provider)}
+
+	// Rounding of converted amounts, e.g. ROUNDING_MODE=half-up
+	if name := os.Getenv("ROUNDING_MODE"); name != "" {
+		mode, err := converter.ParseRoundingMode(name)
+		if err != nil {
+			log.Fatal("Invalid ROUNDING_MODE: ", err)
+		}
+		opts = append(opts, converter.WithRoundingMode(mode))
+	}
+
+	// Consistency of opposite rates: INVERSE_POLICY=independent (default),
+	// derive or reject, with INVERSE_TOLERANCE in percent for reject
+	if name := os.Getenv("INVERSE_POLICY"); name != "" {
+		policy, err := converter.ParseInversePolicy(name)
+		if err != nil {
+			log.Fatal("Invalid INVERSE_POLICY: ", err)
+		}
+		var tolerance decimal.Decimal
+		if value := os.Getenv("INVERSE_TOLERANCE"); value != "" {
+			if tolerance, err = decimal.NewFromString(value); err != nil {
+				log.Fatal("Invalid INVERSE_TOLERANCE: ", err)
+			}
+		}
+		opts = append(opts, converter.WithInversePolicy(policy, tolerance))
+	}
+
+	// Bank spreads and fees: PRICING_PROFILES names a JSON or YAML file
+	if path := os.Getenv("PRICING_PROFILES"); path != "" {
+		defaultProfile, profiles, err := config.LoadPricingProfiles(path)
+		if err != nil {
+			log.Fatal("Failed to load pricing profiles: ", err)
+		}
+		opts = append(opts, converter.WithPricingProfiles(defaultProfile, profiles...))
+	}
+
+	// Create currency converter instance, instrumented for /metrics
+	m := metrics.New()
+	conv := metrics.Instrument(converter.NewCurrencyConverter(opts...), m)
+
+	// Integrity of the rate table at startup: RATE_INTEGRITY_CHECK=warn
+	// (default) logs problems, strict refuses to start, off skips the check.
+	// RATE_INTEGRITY_TOLERANCE is the allowed cycle deviation in percent.
+	checkIntegrity(conv)
+
+	// Audit log of rate changes: AUDIT_LOG_PATH appends it to a JSON lines
+	// file, otherwise it is kept in memory
+	auditLog, err := audit.Open(os.Getenv("AUDIT_LOG_PATH"))
+	if err != nil {
+		log.Fatal("Failed to open audit log: ", err)
+	}
+	defer auditLog.Close()
+
+	// External rate feed: RATE_FEED_URL enables scheduled refreshes
+	if url := os.Getenv("RATE_FEED_URL"); url != "" {
+		feedCfg := feedConfig(url)
+		feedCfg.Audit = auditLog
+		refresher, err := feed.NewRefresher(conv, feedCfg)
+		if err != nil {
+			log.Fatal("Invalid rate feed configuration: ", err)
+		}
+		go refresher.Run(context.Background())
+	}
 
 	// Setup routes
-	router := api.SetupRoutes(conv)
+	cfg := apiConfig()
+	cfg.Metrics = m
+	cfg.Audit = auditLog
+	router := api.SetupRoutes(conv, cfg)
+
+	// gRPC API on GRPC_ADDR (default :9085), sharing the converter and the
+	// access control settings; GRPC_ADDR=off disables it
+	if addr := os.Getenv("GRPC_ADDR"); addr != "off" {
+		if addr == "" {
+			addr = ":9085"
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC: ", err)
+		}
+		server := grpcapi.NewServer(conv, grpcapi.Config{
+			Principals:    cfg.Principals,
+			AnonymousRole: cfg.AnonymousRole,
+			Audit:         auditLog,
+		})
+		log.Printf("gRPC API listening on %s...", addr)
+		go func() {
+			log.Fatal(server.Serve(listener))
+		}()
+	}
 
 	// Start server
 	log.Println("Currency Converter Service starting on port 8085...")
 	log.Fatal(http.ListenAndServe(":8085", router))
 }
+
+// feedConfig reads the rate feed settings from the environment:
+// RATE_FEED_FORMAT (ecb or json, guessed from the URL when unset),
+// RATE_FEED_INTERVAL, RATE_FEED_RETRIES, RATE_FEED_BACKOFF and
+// RATE_FEED_ON_FAILURE (keep or drop)
+func feedConfig(url string) feed.Config {
+	cfg := feed.Config{URL: url, Format: feed.FormatJSON, Retries: 3}
+	if strings.HasSuffix(strings.ToLower(url), ".xml") {
+		cfg.Format = feed.FormatECB
+	}
+
+	var err error
+	if name := os.Getenv("RATE_FEED_FORMAT"); name != "" {
+		if cfg.Format, err = feed.ParseFormat(name); err != nil {
+			log.Fatal("Invalid RATE_FEED_FORMAT: ", err)
+		}
+	}
+	if value := os.Getenv("RATE_FEED_INTERVAL"); value != "" {
+		if cfg.Interval, err = time.ParseDuration(value); err != nil {
+			log.Fatal("Invalid RATE_FEED_INTERVAL: ", err)
+		}
+	}
+	if value := os.Getenv("RATE_FEED_RETRIES"); value != "" {
+		if cfg.Retries, err = strconv.Atoi(value); err != nil {
+			log.Fatal("Invalid RATE_FEED_RETRIES: ", err)
+		}
+	}
+	if value := os.Getenv("RATE_FEED_BACKOFF"); value != "" {
+		if cfg.Backoff, err = time.ParseDuration(value); err != nil {
+			log.Fatal("Invalid RATE_FEED_BACKOFF: ", err)
+		}
+	}
+	if name := os.Getenv("RATE_FEED_ON_FAILURE"); name != "" {
+		if cfg.OnFailure, err = feed.ParseFailurePolicy(name); err != nil {
+			log.Fatal("Invalid RATE_FEED_ON_FAILURE: ", err)
+		}
+	}
+	return cfg
+}
+
+// checkIntegrity runs the rate table integrity check configured by
+// RATE_INTEGRITY_CHECK and RATE_INTEGRITY_TOLERANCE
+func checkIntegrity(conv converter.ICurrencyConverter) {
+	if err := integrityCheck(conv, os.Getenv("RATE_INTEGRITY_CHECK"), os.Getenv("RATE_INTEGRITY_TOLERANCE")); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// integrityCheck checks the rate table in the given mode and tolerance. It
+// returns an error if the service must not start: for an invalid setting, or
+// for problems found in strict mode.
+func integrityCheck(conv converter.ICurrencyConverter, mode, toleranceValue string) error {
+	mode = strings.ToLower(mode)
+	switch mode {
+	case "off":
+		return nil
+	case "", "warn", "strict":
+	default:
+		return fmt.Errorf("invalid RATE_INTEGRITY_CHECK %q (want off, warn or strict)", mode)
+	}
+
+	tolerance := converter.DefaultArbitrageTolerance
+	if toleranceValue != "" {
+		var err error
+		if tolerance, err = decimal.NewFromString(toleranceValue); err != nil {
+			return fmt.Errorf("invalid RATE_INTEGRITY_TOLERANCE: %v", err)
+		}
+	}
+
+	report := conv.CheckIntegrity(tolerance)
+	switch {
+	case report.Healthy():
+		log.Printf("Rate table integrity check passed: %s", report.Summary())
+	case mode == "strict":
+		return fmt.Errorf("rate table integrity check failed: %s", report.Summary())
+	default:
+		log.Printf("Rate table integrity check found problems: %s", report.Summary())
+	}
+	return nil
+}
+
+// apiConfig reads the access control, CORS and versioning settings from the
+// environment. PRINCIPALS_FILE lists API keys and their roles; ADMIN_TOKEN additionally
+// registers a key with the rate-admin role. ANONYMOUS_ROLE is the role of
+// requests without a key: converter by default, or none to require keys.
+func apiConfig() api.Config {
+	cfg := api.Config{
+		Principals:    auth.NewPrincipals(),
+		AnonymousRole: auth.RoleConverter,
+		CORS:          corsConfig(),
+	}
+
+	if path := os.Getenv("PRINCIPALS_FILE"); path != "" {
+		principals, err := config.LoadPrincipals(path)
+		if err != nil {
+			log.Fatal("Failed to load principals: ", err)
+		}
+		cfg.Principals = principals
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		admin := auth.Principal{Name: "admin", Roles: []auth.Role{auth.RoleRateAdmin}}
+		if err := cfg.Principals.Add(auth.HashKey(token), admin); err != nil {
+			log.Fatal("Invalid ADMIN_TOKEN: ", err)
+		}
+	}
+
+	switch name := os.Getenv("ANONYMOUS_ROLE"); name {
+	case "":
+	case "none":
+		cfg.AnonymousRole = ""
+	default:
+		role, err := auth.ParseRole(name)
+		if err != nil {
+			log.Fatal("Invalid ANONYMOUS_ROLE: ", err)
+		}
+		cfg.AnonymousRole = role
+	}
+
+	// API_SUNSET announces when the deprecated unversioned paths stop working
+	if value := os.Getenv("API_SUNSET"); value != "" {
+		sunset, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			log.Fatal("Invalid API_SUNSET (want YYYY-MM-DD): ", err)
+		}
+		cfg.Sunset = sunset
+	}
+
+	log.Printf("Access control: %d API keys, anonymous role %q", cfg.Principals.Len(), cfg.AnonymousRole)
+	return cfg
+}
+
+// corsConfig reads the CORS policy from the environment. CORS_ALLOWED_ORIGINS,
+// CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS and CORS_EXPOSED_HEADERS are
+// comma-separated lists; CORS_ALLOW_CREDENTIALS is a boolean and CORS_MAX_AGE
+// a duration. Without CORS_ALLOWED_ORIGINS no origin is allowed.
+func corsConfig() api.CORSConfig {
+	cfg := api.DefaultCORSConfig()
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		cfg.AllowedOrigins = splitList(value)
+	}
+	if value := os.Getenv("CORS_ALLOWED_METHODS"); value != "" {
+		cfg.AllowedMethods = splitList(strings.ToUpper(value))
+	}
+	if value := os.Getenv("CORS_ALLOWED_HEADERS"); value != "" {
+		cfg.AllowedHeaders = splitList(value)
+	}
+	if value := os.Getenv("CORS_EXPOSED_HEADERS"); value != "" {
+		cfg.ExposedHeaders = splitList(value)
+	}
+
+	var err error
+	if value := os.Getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		if cfg.AllowCredentials, err = strconv.ParseBool(value); err != nil {
+			log.Fatal("Invalid CORS_ALLOW_CREDENTIALS: ", err)
+		}
+	}
+	if value := os.Getenv("CORS_MAX_AGE"); value != "" {
+		if cfg.MaxAge, err = time.ParseDuration(value); err != nil {
+			log.Fatal("Invalid CORS_MAX_AGE: ", err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestIntegrityCheckModes(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	// 0.8 one way and 1.3 back leaves 4% of arbitrage
+	table := make(converter.RateTable)
+	table.Set("USD", "EUR", converter.RateEntry{Rate: decimal.RequireFromString("0.8"), Source: converter.SourceManual})
+	table.Set("EUR", "USD", converter.RateEntry{Rate: decimal.RequireFromString("1.3"), Source: converter.SourceManual})
+	conv := converter.NewCurrencyConverter(converter.WithRateProvider(converter.NewMemoryRateProvider(table)))
+
+	tests := []struct {
+		mode, tolerance string
+		fails           bool
+	}{
+		{"strict", "", true},
+		{"STRICT", "1", true},
+		{"strict", "5", false},
+		{"warn", "", false},
+		{"", "", false},
+		{"off", "", false},
+		// off skips the check, whatever the tolerance
+		{"off", "some", false},
+		{"fatal", "", true},
+		{"warn", "some", true},
+	}
+	for _, tt := range tests {
+		err := integrityCheck(conv, tt.mode, tt.tolerance)
+		if (err != nil) != tt.fails {
+			t.Errorf("mode %q, tolerance %q: err = %v, want failure %v", tt.mode, tt.tolerance, err, tt.fails)
+		}
+	}
+}
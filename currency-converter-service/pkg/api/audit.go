@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultAuditLimit is how many entries GET /rates/audit returns without a
+// limit parameter
+const defaultAuditLimit = 100
+
+// AuditHandler handles GET /rates/audit?from=&to=&currency=&principal=&operation=&start=&end=&limit=
+// Entries are returned oldest first; limit keeps the newest ones.
+func (h *Handler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Currency:  query.Get("currency"),
+		Principal: query.Get("principal"),
+		Operation: query.Get("operation"),
+		Limit:     defaultAuditLimit,
+	}
+
+	var err error
+	if value := query.Get("start"); value != "" {
+		if filter.Start, err = parseTime(value, false); err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return
+		}
+	}
+	if value := query.Get("end"); value != "" {
+		if filter.End, err = parseTime(value, true); err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 || filter.Limit > 1000 {
+			h.writeError(w, http.StatusBadRequest, "INVALID_LIMIT", "limit must be a number between 1 and 1000")
+			return
+		}
+	}
+
+	h.writeJSON(w, http.StatusOK, models.AuditLogResponse{Entries: auditEntryResponses(h.auditLog.Query(filter))})
+}
+
+// RollbackHandler handles POST /rates/audit/{id}/rollback
+// It restores the pair of the entry to the rate the entry set.
+func (h *Handler) RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RollbackRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "AUDIT_ENTRY_NOT_FOUND", "audit entry does not exist")
+		return
+	}
+
+	entries, err := h.auditLog.Rollback(h.converter, id, h.auditChange(r, audit.OpRollback, req.Reason))
+	if err != nil {
+		var convErr converter.ConversionError
+		switch {
+		case !errors.As(err, &convErr):
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		case convErr.Code == "AUDIT_ENTRY_NOT_FOUND":
+			h.writeError(w, http.StatusNotFound, convErr.Code, convErr.Message)
+		case convErr.Code == "ROLLBACK_UNSUPPORTED":
+			h.writeError(w, http.StatusConflict, convErr.Code, convErr.Message)
+		default:
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, models.RollbackResponse{Status: "rolled back", Entries: auditEntryResponses(entries)})
+}
+
+// auditChange attributes a change to the principal and request ID of a request
+func (h *Handler) auditChange(r *http.Request, operation, reason string) audit.Change {
+	principal, _ := auth.PrincipalFrom(r.Context())
+	return audit.Change{
+		Operation: operation,
+		Principal: principal.Name,
+		RequestID: RequestID(r.Context()),
+		Reason:    reason,
+	}
+}
+
+// auditEntryResponses converts audit entries for the API
+func auditEntryResponses(entries []audit.Entry) []models.AuditEntryResponse {
+	responses := make([]models.AuditEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, models.AuditEntryResponse(entry))
+	}
+	return responses
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+)
+
+func testPrincipals(t *testing.T) *auth.Principals {
+	t.Helper()
+	principals := auth.NewPrincipals()
+	for key, principal := range map[string]auth.Principal{
+		"viewer-key":    {Name: "dashboard", Roles: []auth.Role{auth.RoleViewer}},
+		"converter-key": {Name: "checkout", Roles: []auth.Role{auth.RoleConverter}},
+		"admin-key":     {Name: "treasury", Roles: []auth.Role{auth.RoleRateAdmin}},
+	} {
+		if err := principals.Add(auth.HashKey(key), principal); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	return principals
+}
+
+func TestRoleBasedAccess(t *testing.T) {
+	auditLog, _ := audit.Open("")
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{Principals: testPrincipals(t), Audit: auditLog})
+
+	convert := `{"amount": "10", "from": "USD", "to": "EUR"}`
+	setRate := `{"from": "USD", "to": "EUR", "rate": "0.9", "reason": "desk"}`
+	tests := []struct {
+		method, path, body, key string
+		status                  int
+		code                    string
+	}{
+		{"GET", "/v2/currencies", "", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"GET", "/v2/currencies", "", "unknown-key", http.StatusUnauthorized, "INVALID_CREDENTIALS"},
+		{"GET", "/v2/currencies", "", "viewer-key", http.StatusOK, ""},
+		{"GET", "/v2/rates/USD/EUR", "", "admin-key", http.StatusOK, ""},
+		{"POST", "/v2/convert", convert, "viewer-key", http.StatusForbidden, "FORBIDDEN"},
+		{"POST", "/v2/convert", convert, "converter-key", http.StatusOK, ""},
+		{"POST", "/v1/convert", convert, "converter-key", http.StatusOK, ""},
+		{"POST", "/v2/rates", setRate, "converter-key", http.StatusForbidden, "FORBIDDEN"},
+		{"POST", "/v1/rates", setRate, "viewer-key", http.StatusForbidden, "FORBIDDEN"},
+		{"DELETE", "/v2/currencies/JPY", "", "converter-key", http.StatusForbidden, "FORBIDDEN"},
+		{"POST", "/v2/rates", setRate, "admin-key", http.StatusOK, ""},
+		{"GET", "/v2/rates/audit", "", "viewer-key", http.StatusOK, ""},
+		{"POST", "/v2/rates/audit/1/rollback", `{"reason": "undo"}`, "converter-key", http.StatusForbidden, "FORBIDDEN"},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{}
+		if tt.key != "" {
+			headers["X-API-Key"] = tt.key
+		}
+		rec := serve(routes, tt.method, tt.path, tt.body, headers)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s as %q: status = %d, want %d (%s)", tt.method, tt.path, tt.key, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.code != "" {
+			var resp models.ErrorResponse
+			decodeBody(t, rec, &resp)
+			if resp.Code != tt.code {
+				t.Errorf("%s %s as %q: code = %s, want %s", tt.method, tt.path, tt.key, resp.Code, tt.code)
+			}
+		}
+	}
+
+	entries := auditLog.Query(audit.Filter{})
+	if len(entries) != 1 || entries[0].Principal != "treasury" || entries[0].Reason != "desk" {
+		t.Errorf("audit log = %+v, want one change by treasury", entries)
+	}
+}
+
+func TestBearerKeyAndAnonymousRole(t *testing.T) {
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{Principals: testPrincipals(t), AnonymousRole: auth.RoleViewer})
+
+	if rec := serve(routes, "GET", "/v2/rates", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("anonymous viewer: status = %d, want 200", rec.Code)
+	}
+	if rec := serve(routes, "POST", "/v2/convert", `{"amount": "1", "from": "USD", "to": "EUR"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous convert: status = %d, want 401", rec.Code)
+	}
+	rec := serve(routes, "DELETE", "/v2/rates", "", map[string]string{"Authorization": "Bearer admin-key"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("bearer admin reset: status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+}
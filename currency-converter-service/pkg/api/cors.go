@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig is the cross-origin policy of the API. Origins are matched
+// exactly, except for "*", which allows every origin, and a "*." label such
+// as "https://*.example.edu", which allows any subdomain.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// DefaultCORSConfig allows no origins; the methods and headers apply once
+// origins are added
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", RequestIDHeader},
+		ExposedHeaders: []string{RequestIDHeader},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// Validate checks that the policy can be served safely. Credentials cannot be
+// combined with the "*" origin: browsers refuse that, and echoing the origin
+// instead would let every site make authenticated calls.
+func (c CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return fmt.Errorf("CORS credentials cannot be allowed for every origin (*); list the origins instead")
+		}
+	}
+	return nil
+}
+
+// allowsOrigin reports whether an origin may call the API
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(strings.ToLower(allowed), "*"); ok {
+			origin := strings.ToLower(origin)
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				// the wildcard stands for subdomain labels only, never a path or port
+				if label := origin[len(prefix) : len(origin)-len(suffix)]; !strings.ContainsAny(label, "/:") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// allowsMethod reports whether a method may be used cross-origin
+func (c CORSConfig) allowsMethod(method string) bool {
+	for _, allowed := range c.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether every header of a comma-separated
+// Access-Control-Request-Headers list may be sent
+func (c CORSConfig) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, h := range c.AllowedHeaders {
+			if strings.EqualFold(h, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// CORSMiddleware applies a CORS policy. Requests from origins outside the
+// policy get no CORS headers, so browsers refuse them. Preflight requests are
+// answered here: 204 if the origin, requested method and requested headers
+// are allowed, 403 otherwise. A "*" origin never allows credentials, even in
+// a policy that fails Validate.
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	wildcard := false
+	for _, origin := range cfg.AllowedOrigins {
+		wildcard = wildcard || origin == "*"
+	}
+	credentials := cfg.AllowCredentials && !wildcard
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := cfg.allowsOrigin(origin)
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if !allowed ||
+					!cfg.allowsMethod(r.Header.Get("Access-Control-Request-Method")) ||
+					!cfg.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+					writeAuthError(w, http.StatusForbidden, "CORS_REJECTED", "Cross-origin request not allowed")
+					return
+				}
+			}
+			if !allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wildcard {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(cfg.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+			if len(cfg.AllowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+			}
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+)
+
+func corsRoutes(origins []string, credentials bool) http.Handler {
+	cors := DefaultCORSConfig()
+	cors.AllowedOrigins = origins
+	cors.AllowCredentials = credentials
+	return newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer, CORS: cors})
+}
+
+func TestCORSPreflight(t *testing.T) {
+	routes := corsRoutes([]string{"https://app.example.com", "https://*.example.edu"}, false)
+
+	tests := []struct {
+		name, path, origin, method, headers string
+		status                              int
+	}{
+		{"allowed", "/v2/convert", "https://app.example.com", "POST", "Content-Type, X-API-Key", http.StatusNoContent},
+		{"unversioned path", "/convert", "https://app.example.com", "POST", "", http.StatusNoContent},
+		{"unknown path", "/v2/nope", "https://app.example.com", "GET", "", http.StatusNoContent},
+		{"subdomain", "/v2/rates", "https://lab.cs.example.edu", "GET", "", http.StatusNoContent},
+		{"bare domain", "/v2/rates", "https://example.edu", "GET", "", http.StatusForbidden},
+		{"subdomain with port", "/v2/rates", "https://lab.example.edu:8443", "GET", "", http.StatusForbidden},
+		{"other origin", "/v2/convert", "https://evil.example.net", "POST", "", http.StatusForbidden},
+		{"method", "/v2/rates", "https://app.example.com", "PUT", "", http.StatusForbidden},
+		{"header", "/v2/convert", "https://app.example.com", "POST", "X-Custom", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{"Origin": tt.origin, "Access-Control-Request-Method": tt.method}
+		if tt.headers != "" {
+			headers["Access-Control-Request-Headers"] = tt.headers
+		}
+		rec := serve(routes, http.MethodOptions, tt.path, "", headers)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+		if tt.status == http.StatusNoContent {
+			if allowOrigin != tt.origin || rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("%s: headers = %v", tt.name, rec.Header())
+			}
+			continue
+		}
+		var resp models.ErrorResponse
+		decodeBody(t, rec, &resp)
+		if allowOrigin != "" || resp.Code != "CORS_REJECTED" {
+			t.Errorf("%s: Access-Control-Allow-Origin %q, code %s", tt.name, allowOrigin, resp.Code)
+		}
+	}
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	routes := corsRoutes([]string{"https://app.example.com"}, false)
+
+	rec := serve(routes, http.MethodGet, "/v2/currencies", "", map[string]string{"Origin": "https://app.example.com"})
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get("Access-Control-Expose-Headers") != RequestIDHeader {
+		t.Errorf("allowed origin: status %d, headers %v", rec.Code, rec.Header())
+	}
+
+	// the request is served, but without CORS headers the browser hides the response
+	rec = serve(routes, http.MethodGet, "/v2/currencies", "", map[string]string{"Origin": "https://evil.example.net"})
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("other origin: status %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	origin := map[string]string{"Origin": "https://anywhere.example.org"}
+
+	rec := serve(corsRoutes([]string{"*"}, false), http.MethodGet, "/v2/currencies", "", origin)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+
+	// credentials cannot be combined with "*": the policy is invalid, and
+	// served anyway it allows no credentials rather than echo every origin
+	if err := (CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("Validate accepted credentials for every origin")
+	}
+	rec = serve(corsRoutes([]string{"*"}, true), http.MethodGet, "/v2/currencies", "", origin)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("with credentials: headers %v", rec.Header())
+	}
+
+	for _, origins := range [][]string{{"https://app.example.com"}, {"https://*.example.edu"}} {
+		if err := (CORSConfig{AllowedOrigins: origins, AllowCredentials: true}).Validate(); err != nil {
+			t.Errorf("Validate(%v with credentials): %v", origins, err)
+		}
+	}
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	routes := corsRoutes([]string{"https://app.example.com"}, false)
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound, "NOT_FOUND"},
+		{http.MethodGet, "/v2/nope", http.StatusNotFound, "NOT_FOUND"},
+		{http.MethodPut, "/v2/rates", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED"},
+		{http.MethodOptions, "/v2/rates", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED"},
+	}
+	for _, tt := range tests {
+		rec := serve(routes, tt.method, tt.path, "", nil)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+			continue
+		}
+		var resp models.ErrorResponse
+		decodeBody(t, rec, &resp)
+		if resp.Code != tt.code {
+			t.Errorf("%s %s: code = %s, want %s", tt.method, tt.path, resp.Code, tt.code)
+		}
+	}
+}
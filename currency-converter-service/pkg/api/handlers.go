@@ -2,14 +2,24 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"currency-converter-service/pkg/audit"
 	"currency-converter-service/pkg/converter"
 	"currency-converter-service/pkg/models"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 )
 
 type Handler struct {
 	converter converter.ICurrencyConverter
+	// auditLog records rate changes; nil disables auditing
+	auditLog *audit.Log
 }
 
 // constructor
@@ -20,17 +30,64 @@ func NewHandler(conv converter.ICurrencyConverter) *Handler {
 // ConvertHandler handles POST /convert
 func (h *Handler) ConvertHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.ConvertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	opts, ok := h.convertOptions(w, req.ConversionOptions)
+	if !ok {
+		return
+	}
+
+	result, err := h.converter.ConvertWithResult(req.Amount, req.From, req.To, opts...)
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, convertResponse(result))
+}
+
+// BatchConvertHandler handles POST /convert/batch
+func (h *Handler) BatchConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchConvertRequest
+	if !h.decode(w, r, &req) {
 		return
 	}
 
-	if req.Amount <= 0 {
-		h.writeError(w, http.StatusBadRequest, "INVALID_AMOUNT", "Amount must be greater than zero")
+	opts, ok := h.convertOptions(w, req.ConversionOptions)
+	if !ok {
 		return
 	}
 
-	result, err := h.converter.(*converter.CurrencyConverter).ConvertWithResult(req.Amount, req.From, req.To)
+	// items are validated one by one, so an invalid item fails on its own
+	// instead of rejecting the batch
+	response := models.BatchConvertResponse{Results: make([]models.BatchConvertResult, len(req.Items))}
+	var items []converter.BatchItem
+	var positions []int
+	for i, item := range req.Items {
+		response.Results[i].ID = item.ID
+		if details := validate(&item); len(details) > 0 {
+			response.Results[i].Error = &models.ErrorResponse{
+				Error:   "Item validation failed",
+				Code:    "VALIDATION_FAILED",
+				Details: details,
+			}
+			continue
+		}
+		items = append(items, converter.BatchItem{ID: item.ID, Amount: item.Amount, From: item.From, To: item.To})
+		positions = append(positions, i)
+	}
+	if len(items) == 0 {
+		h.writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	results, err := h.converter.ConvertBatch(items, opts...)
 	if err != nil {
 		if convErr, ok := err.(converter.ConversionError); ok {
 			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
@@ -40,34 +97,221 @@ func (h *Handler) ConvertHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i, result := range results {
+		entry := &response.Results[positions[i]]
+		if result.Error != nil {
+			entry.Error = &models.ErrorResponse{Error: result.Error.Message, Code: result.Error.Code}
+			continue
+		}
+		converted := convertResponse(result.Result)
+		entry.Result = &converted
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// ReverseConvertHandler handles POST /convert/reverse
+func (h *Handler) ReverseConvertHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReverseConvertRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	opts, ok := h.convertOptions(w, req.ConversionOptions)
+	if !ok {
+		return
+	}
+
+	result, err := h.converter.ConvertTo(req.TargetAmount, req.From, req.To, opts...)
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	// the target has the minor units of the result, which follow the
+	// currency registry rather than ISO 4217
+	target := result.NetAmount
+	target.Amount = req.TargetAmount
+	if places := -req.TargetAmount.Exponent(); places > target.Scale {
+		target.Scale = places
+	}
+	h.writeJSON(w, http.StatusOK, models.ReverseConvertResponse{
+		TargetAmount:    target.AmountString(),
+		ConvertResponse: convertResponse(result),
+	})
+}
+
+// convertOptions turns the request options into converter options, writing
+// an error response and returning false if one is invalid
+func (h *Handler) convertOptions(w http.ResponseWriter, req models.ConversionOptions) ([]converter.ConvertOption, bool) {
+	var opts []converter.ConvertOption
+	if req.Date != "" {
+		at, err := parseTime(req.Date, true)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return nil, false
+		}
+		opts = append(opts, converter.AsOf(at))
+	}
+	if req.MaxAge != "" {
+		maxAge, err := parseMaxAge(req.MaxAge)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_MAX_AGE", err.Error())
+			return nil, false
+		}
+		opts = append(opts, converter.MaxAge(maxAge))
+	}
+	if req.Profile != "" {
+		opts = append(opts, converter.WithProfile(req.Profile))
+	}
+	if req.Side != "" {
+		side, err := converter.ParseSide(req.Side)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_SIDE", err.Error())
+			return nil, false
+		}
+		opts = append(opts, converter.WithSide(side))
+	}
+	return opts, true
+}
+
+// convertResponse converts a conversion result for the API
+func convertResponse(result *converter.ConversionResult) models.ConvertResponse {
 	response := models.ConvertResponse{
-		ConvertedAmount: result.ConvertedAmount,
-		OriginalAmount:  result.OriginalAmount,
+		ConvertedAmount: result.ConvertedAmount.AmountString(),
+		OriginalAmount:  result.OriginalAmount.AmountString(),
 		FromCurrency:    result.FromCurrency,
 		ToCurrency:      result.ToCurrency,
-		ExchangeRate:    result.ExchangeRate,
+		ExchangeRate:    result.ExchangeRate.String(),
+		MidRate:         result.MidRate.String(),
+		Profile:         result.Profile,
+		Side:            string(result.Side),
+		Fee:             result.Fee.AmountString(),
+		NetAmount:       result.NetAmount.AmountString(),
+		RouteKind:       string(result.RouteKind),
+		Route:           result.Route,
+		RateSource:      result.RateSource,
+		AsOf:            result.AsOf,
 		Timestamp:       result.Timestamp,
 	}
-
-	h.writeJSON(w, http.StatusOK, response)
+	if !result.RateUpdatedAt.IsZero() {
+		updatedAt := result.RateUpdatedAt
+		age := int64(result.RateAge / time.Second)
+		response.RateUpdatedAt = &updatedAt
+		response.RateAgeSeconds = &age
+	}
+	return response
 }
 
 // CurrenciesHandler handles GET /currencies
 func (h *Handler) CurrenciesHandler(w http.ResponseWriter, r *http.Request) {
-	currencies := h.converter.GetSupportedCurrencies()
-	response := models.CurrenciesResponse{Currencies: currencies}
+	response := models.CurrenciesResponse{Currencies: []models.CurrencyResponse{}}
+	for _, info := range h.converter.ListCurrencies() {
+		response.Currencies = append(response.Currencies, currencyResponse(info))
+	}
 	h.writeJSON(w, http.StatusOK, response)
 }
 
+// AddCurrencyHandler handles POST /currencies
+func (h *Handler) AddCurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.AddCurrencyRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	currency, _ := converter.LookupISO4217(req.Code)
+	currency.Code = req.Code
+	if req.NumericCode != "" {
+		currency.NumericCode = req.NumericCode
+	}
+	if req.Name != "" {
+		currency.Name = req.Name
+	}
+	if req.Symbol != "" {
+		currency.Symbol = req.Symbol
+	}
+	if req.MinorUnits != nil {
+		currency.MinorUnits = *req.MinorUnits
+	} else if _, ok := converter.LookupISO4217(req.Code); !ok {
+		currency.MinorUnits = converter.DefaultMinorUnits
+	}
+
+	if err := h.converter.AddCurrency(currency); err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	for _, info := range h.converter.ListCurrencies() {
+		if strings.EqualFold(info.Code, req.Code) {
+			h.writeJSON(w, http.StatusCreated, currencyResponse(info))
+			return
+		}
+	}
+	h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "currency was not registered")
+}
+
+// RemoveCurrencyHandler handles DELETE /currencies/{code}?reason=
+func (h *Handler) RemoveCurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	change := h.auditChange(r, audit.OpRemoveCurrency, r.URL.Query().Get("reason"))
+	diffs, err := h.auditLog.Apply(h.converter, change, func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.RemoveCurrency(mux.Vars(r)["code"])
+	})
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusNotFound, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	response := models.RemoveCurrencyResponse{Status: "currency removed", Changes: []models.RateChangeResponse{}}
+	for _, diff := range diffs {
+		response.Changes = append(response.Changes, rateChangeResponse(diff))
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// currencyResponse converts a registered currency for the API
+func currencyResponse(info converter.CurrencyInfo) models.CurrencyResponse {
+	return models.CurrencyResponse{
+		Code:        info.Code,
+		NumericCode: info.NumericCode,
+		Name:        info.Name,
+		MinorUnits:  info.MinorUnits,
+		Symbol:      info.Symbol,
+		HasRates:    info.HasRates,
+	}
+}
+
 // SetRateHandler handles POST /rates
 func (h *Handler) SetRateHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.SetRateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+	if !h.decode(w, r, &req) {
 		return
 	}
 
-	if err := h.converter.SetExchangeRate(req.From, req.To, req.Rate); err != nil {
+	effective := time.Now()
+	if req.EffectiveAt != "" {
+		var err error
+		if effective, err = parseTime(req.EffectiveAt, false); err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return
+		}
+	}
+
+	_, err := h.auditLog.Apply(h.converter, h.auditChange(r, audit.OpSet, req.Reason), func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.SetExchangeRateAt(req.From, req.To, req.Rate, effective)
+	})
+	if err != nil {
 		if convErr, ok := err.(converter.ConversionError); ok {
 			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
 		} else {
@@ -80,11 +324,234 @@ func (h *Handler) SetRateHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
-// ResetRatesHandler handles DELETE /rates
+// RatesHandler handles GET /rates?base=
+func (h *Handler) RatesHandler(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(r.URL.Query().Get("base"))
+
+	response := models.RatesResponse{Base: base, Rates: []models.RateResponse{}}
+	for _, quote := range h.converter.ListRates(base) {
+		response.Rates = append(response.Rates, rateResponse(quote))
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// RateHandler handles GET /rates/{from}/{to}
+func (h *Handler) RateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	quote, err := h.converter.GetRateQuote(vars["from"], vars["to"])
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusNotFound, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, rateResponse(*quote))
+}
+
+// rateResponse converts a quote for the read API
+func rateResponse(quote converter.RateQuote) models.RateResponse {
+	response := models.RateResponse{
+		From:      quote.From,
+		To:        quote.To,
+		Rate:      quote.Rate.String(),
+		Source:    quote.Source,
+		RouteKind: string(quote.RouteKind),
+		Route:     quote.Route,
+	}
+	if !quote.UpdatedAt.IsZero() {
+		updatedAt := quote.UpdatedAt
+		response.UpdatedAt = &updatedAt
+	}
+	return response
+}
+
+// RateHistoryHandler handles GET /rates/history?from=&to=&start=&end=
+func (h *Handler) RateHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from := strings.ToUpper(query.Get("from"))
+	to := strings.ToUpper(query.Get("to"))
+	if from == "" || to == "" {
+		h.writeError(w, http.StatusBadRequest, "INVALID_PAIR", "from and to are required")
+		return
+	}
+
+	var start, end time.Time
+	var err error
+	if value := query.Get("start"); value != "" {
+		if start, err = parseTime(value, false); err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return
+		}
+	}
+	if value := query.Get("end"); value != "" {
+		if end, err = parseTime(value, true); err != nil {
+			h.writeError(w, http.StatusBadRequest, "INVALID_DATE", err.Error())
+			return
+		}
+	}
+	if !end.IsZero() && end.Before(start) {
+		h.writeError(w, http.StatusBadRequest, "INVALID_DATE", "end must not be before start")
+		return
+	}
+
+	response := models.RateHistoryResponse{From: from, To: to, Points: []models.RatePointResponse{}}
+	for _, point := range h.converter.GetRateHistory(from, to, start, end) {
+		entry := models.RatePointResponse{EffectiveAt: point.EffectiveAt, Source: point.Source, Removed: point.Removed}
+		if !point.Removed {
+			entry.Rate = point.Rate.String()
+		}
+		response.Points = append(response.Points, entry)
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// InverseAuditHandler handles GET /rates/consistency?threshold=
+// threshold is the allowed deviation of rate(a,b)*rate(b,a) from 1, in percent.
+func (h *Handler) InverseAuditHandler(w http.ResponseWriter, r *http.Request) {
+	threshold := converter.DefaultInverseTolerance
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		var err error
+		threshold, err = decimal.NewFromString(value)
+		if err != nil || threshold.Sign() < 0 {
+			h.writeError(w, http.StatusBadRequest, "INVALID_THRESHOLD", "threshold must be a non-negative percentage")
+			return
+		}
+	}
+
+	response := models.InverseAuditResponse{ThresholdPercent: threshold.String(), Pairs: []models.InverseDeviationResponse{}}
+	for _, deviation := range h.converter.AuditInverseRates(threshold) {
+		response.Pairs = append(response.Pairs, models.InverseDeviationResponse{
+			From:             deviation.Pair.From,
+			To:               deviation.Pair.To,
+			Rate:             deviation.Rate.String(),
+			InverseRate:      deviation.InverseRate.String(),
+			Product:          deviation.Product.String(),
+			DeviationPercent: deviation.DeviationPercent.StringFixed(4),
+		})
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// RateHealthHandler handles GET /rates/health?tolerance=
+// It answers 503 Service Unavailable when the table has arbitrage cycles above
+// the tolerance (in percent) or pairs that cannot be converted.
+func (h *Handler) RateHealthHandler(w http.ResponseWriter, r *http.Request) {
+	tolerance := converter.DefaultArbitrageTolerance
+	if value := r.URL.Query().Get("tolerance"); value != "" {
+		var err error
+		tolerance, err = decimal.NewFromString(value)
+		if err != nil || tolerance.Sign() < 0 {
+			h.writeError(w, http.StatusBadRequest, "INVALID_TOLERANCE", "tolerance must be a non-negative percentage")
+			return
+		}
+	}
+
+	report := h.converter.CheckIntegrity(tolerance)
+	response := models.RateHealthResponse{
+		Healthy:          report.Healthy(),
+		Summary:          report.Summary(),
+		CheckedAt:        report.CheckedAt,
+		TolerancePercent: report.TolerancePercent.String(),
+		Currencies:       report.Currencies,
+		Cycles:           []models.ArbitrageCycleResponse{},
+		MissingPairs:     pairResponses(report.MissingPairs),
+		Unreachable:      pairResponses(report.Unreachable),
+	}
+	for _, cycle := range report.Cycles {
+		response.Cycles = append(response.Cycles, models.ArbitrageCycleResponse{
+			Currencies:       cycle.Currencies,
+			Product:          cycle.Product.String(),
+			DeviationPercent: cycle.DeviationPercent.StringFixed(4),
+		})
+	}
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeJSON(w, status, response)
+}
+
+// pairResponses converts a list of pairs for the API
+func pairResponses(pairs []converter.Pair) []models.PairResponse {
+	responses := []models.PairResponse{}
+	for _, pair := range pairs {
+		responses = append(responses, models.PairResponse{From: pair.From, To: pair.To})
+	}
+	return responses
+}
+
+// ResetRatesHandler handles DELETE /rates?from=&to=&reason=
+// Without parameters every rate is reset; from alone resets one base currency
+// and from with to resets a single pair.
 func (h *Handler) ResetRatesHandler(w http.ResponseWriter, r *http.Request) {
-	h.converter.ResetRates()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "rates reset"})
+	scope := converter.ResetScope{
+		From: r.URL.Query().Get("from"),
+		To:   r.URL.Query().Get("to"),
+	}
+
+	change := h.auditChange(r, audit.OpReset, r.URL.Query().Get("reason"))
+	diffs, err := h.auditLog.Apply(h.converter, change, func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.ResetRates(scope)
+	})
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	response := models.ResetRatesResponse{Status: "rates reset", Changes: []models.RateChangeResponse{}}
+	for _, diff := range diffs {
+		response.Changes = append(response.Changes, rateChangeResponse(diff))
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// rateChangeResponse converts a rate diff, leaving out the side that is absent
+func rateChangeResponse(diff converter.RateDiff) models.RateChangeResponse {
+	change := models.RateChangeResponse{From: diff.Pair.From, To: diff.Pair.To}
+	if !diff.Previous.IsZero() {
+		change.Previous = diff.Previous.String()
+	}
+	if !diff.Current.IsZero() {
+		change.Current = diff.Current.String()
+	}
+	return change
+}
+
+// parseTime accepts RFC 3339 timestamps or plain dates. A plain date means the
+// start of that day in UTC, or its last instant when endOfDay is set.
+func parseTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// parseMaxAge accepts a Go duration such as "36h" or a whole number of seconds
+func parseMaxAge(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid maxAge %q, expected a positive duration such as \"36h\" or seconds", value)
+	}
+	return d, nil
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestRoutes sets up the API with a discarded access log
+func newTestRoutes(conv converter.ICurrencyConverter, cfg Config) http.Handler {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return SetupRoutes(conv, cfg)
+}
+
+// serve sends a request with the given headers and returns the recorded response
+func serve(h http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestReverseConvertUsesRegisteredMinorUnits(t *testing.T) {
+	conv := converter.NewCurrencyConverter()
+	if err := conv.AddCurrency(converter.Currency{Code: "XAB", Name: "Test unit", MinorUnits: 4}); err != nil {
+		t.Fatalf("AddCurrency: %v", err)
+	}
+	if err := conv.SetExchangeRate("USD", "XAB", decimal.RequireFromString("2")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+	routes := newTestRoutes(conv, Config{AnonymousRole: auth.RoleConverter})
+
+	rec := serve(routes, http.MethodPost, "/v2/convert/reverse", `{"targetAmount": "1", "from": "USD", "to": "XAB"}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp models.ReverseConvertResponse
+	decodeBody(t, rec, &resp)
+	if resp.TargetAmount != "1.0000" || resp.NetAmount != "1.0000" || resp.OriginalAmount != "0.50" {
+		t.Errorf("target %s, net %s, source %s; want 1.0000, 1.0000 and 0.50",
+			resp.TargetAmount, resp.NetAmount, resp.OriginalAmount)
+	}
+}
+
+func TestBatchReportsInvalidItemsPerItem(t *testing.T) {
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleConverter})
+
+	body := `{"items": [
+		{"id": "ok", "amount": "10", "from": "USD", "to": "EUR"},
+		{"id": "short", "amount": "10", "from": "USD", "to": "EU"},
+		{"id": "zero", "amount": "0", "from": "USD", "to": "EUR"},
+		{"id": "unknown", "amount": "10", "from": "USD", "to": "XYZ"}
+	]}`
+	rec := serve(routes, http.MethodPost, "/v2/convert/batch", body, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp models.BatchConvertResponse
+	decodeBody(t, rec, &resp)
+	if len(resp.Results) != 4 {
+		t.Fatalf("results = %+v, want 4", resp.Results)
+	}
+	if got := resp.Results[0]; got.ID != "ok" || got.Result == nil || got.Result.ConvertedAmount != "8.50" {
+		t.Errorf("valid item = %+v, want 8.50 EUR", got)
+	}
+	for i, want := range []struct{ id, code, field string }{
+		{"short", "VALIDATION_FAILED", "to"},
+		{"zero", "VALIDATION_FAILED", "amount"},
+		{"unknown", "RATE_NOT_FOUND", ""},
+	} {
+		got := resp.Results[i+1]
+		if got.ID != want.id || got.Result != nil || got.Error == nil || got.Error.Code != want.code {
+			t.Errorf("item %s = %+v, want error %s", want.id, got, want.code)
+			continue
+		}
+		if want.field != "" && (len(got.Error.Details) != 1 || got.Error.Details[0].Field != want.field) {
+			t.Errorf("item %s details = %+v, want field %s", want.id, got.Error.Details, want.field)
+		}
+	}
+
+	rec = serve(routes, http.MethodPost, "/v2/convert/batch", `{"items": []}`, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestRateHealthEndpoint(t *testing.T) {
+	// 0.8 one way and 1.3 back leaves 4% of arbitrage
+	table := make(converter.RateTable)
+	table.Set("USD", "EUR", converter.RateEntry{Rate: decimal.RequireFromString("0.8"), Source: converter.SourceManual})
+	table.Set("EUR", "USD", converter.RateEntry{Rate: decimal.RequireFromString("1.3"), Source: converter.SourceManual})
+	conv := converter.NewCurrencyConverter(converter.WithRateProvider(converter.NewMemoryRateProvider(table)))
+	routes := newTestRoutes(conv, Config{AnonymousRole: auth.RoleViewer})
+
+	rec := serve(routes, http.MethodGet, "/v2/rates/health", "", nil)
+	var health models.RateHealthResponse
+	decodeBody(t, rec, &health)
+	if rec.Code != http.StatusServiceUnavailable || health.Healthy || health.TolerancePercent != "0.5" || len(health.Cycles) != 1 {
+		t.Fatalf("default tolerance: status %d, health %+v", rec.Code, health)
+	}
+	if cycle := health.Cycles[0]; strings.Join(cycle.Currencies, ",") != "EUR,USD,EUR" || cycle.DeviationPercent != "4.0000" {
+		t.Errorf("cycle = %+v, want EUR,USD,EUR at 4.0000%%", cycle)
+	}
+
+	rec = serve(routes, http.MethodGet, "/v2/rates/health?tolerance=5", "", nil)
+	health = models.RateHealthResponse{}
+	decodeBody(t, rec, &health)
+	if rec.Code != http.StatusOK || !health.Healthy || len(health.Cycles) != 0 {
+		t.Errorf("tolerance 5: status %d, health %+v", rec.Code, health)
+	}
+
+	for _, tolerance := range []string{"some", "-1"} {
+		rec := serve(routes, http.MethodGet, "/v2/rates/health?tolerance="+tolerance, "", nil)
+		var resp models.ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != "INVALID_TOLERANCE" {
+			t.Errorf("tolerance %s: status %d, code %s; want 400 INVALID_TOLERANCE", tolerance, rec.Code, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+)
+
+// ConvertV1Handler handles POST /v1/convert
+func (h *Handler) ConvertV1Handler(w http.ResponseWriter, r *http.Request) {
+	var req models.ConvertRequestV1
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	result, err := h.converter.ConvertWithResult(req.Amount, req.From, req.To)
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, models.ConvertResponseV1{
+		ConvertedAmount: result.NetAmount.Amount.InexactFloat64(),
+		OriginalAmount:  result.OriginalAmount.Amount.InexactFloat64(),
+		FromCurrency:    result.FromCurrency,
+		ToCurrency:      result.ToCurrency,
+		ExchangeRate:    result.ExchangeRate.InexactFloat64(),
+		Timestamp:       result.Timestamp,
+	})
+}
+
+// CurrenciesV1Handler handles GET /v1/currencies
+func (h *Handler) CurrenciesV1Handler(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, models.CurrenciesResponseV1{Currencies: h.converter.GetSupportedCurrencies()})
+}
+
+// SetRateV1Handler handles POST /v1/rates
+func (h *Handler) SetRateV1Handler(w http.ResponseWriter, r *http.Request) {
+	var req models.SetRateRequestV1
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	_, err := h.auditLog.Apply(h.converter, h.auditChange(r, audit.OpSet, ""), func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.SetExchangeRateAt(req.From, req.To, req.Rate, time.Now())
+	})
+	if err != nil {
+		if convErr, ok := err.(converter.ConversionError); ok {
+			h.writeError(w, http.StatusBadRequest, convErr.Code, convErr.Message)
+		} else {
+			h.writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
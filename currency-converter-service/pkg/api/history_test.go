@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		endOfDay bool
+		want     time.Time
+	}{
+		{"2026-03-01", false, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01", true, time.Date(2026, time.March, 1, 23, 59, 59, 999999999, time.UTC)},
+		// explicit times are taken as given, whatever the end of day rule
+		{"2026-03-01T12:30:00Z", true, time.Date(2026, time.March, 1, 12, 30, 0, 0, time.UTC)},
+		{"2026-03-01T12:30:00+02:00", false, time.Date(2026, time.March, 1, 10, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.value, tt.endOfDay)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q, %v) = %s, %v; want %s", tt.value, tt.endOfDay, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"01/03/2026", "2026-3-1", "2026-03-01 12:30"} {
+		if _, err := parseTime(value, false); err == nil {
+			t.Errorf("parseTime(%q) succeeded", value)
+		}
+	}
+}
+
+// historyRoutes serves a converter whose EUR/USD rate changed to 1.25 at 18:00
+// UTC yesterday and returns yesterday's date
+func historyRoutes(t *testing.T) (http.Handler, time.Time) {
+	t.Helper()
+	yesterday := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	conv := converter.NewCurrencyConverter()
+	if _, err := conv.SetExchangeRateAt("EUR", "USD", decimal.RequireFromString("1.25"), yesterday.Add(18*time.Hour)); err != nil {
+		t.Fatalf("SetExchangeRateAt: %v", err)
+	}
+	return newTestRoutes(conv, Config{AnonymousRole: auth.RoleConverter}), yesterday
+}
+
+func TestConvertDateOption(t *testing.T) {
+	routes, yesterday := historyRoutes(t)
+
+	tests := []struct {
+		name, date, want string
+	}{
+		{"no date", "", "125.00"},
+		{"day of the update, up to its end", yesterday.Format("2006-01-02"), "125.00"},
+		{"day before the update", yesterday.AddDate(0, 0, -1).Format("2006-01-02"), "118.00"},
+		{"time before the update", yesterday.Add(12 * time.Hour).Format(time.RFC3339), "118.00"},
+		{"time after the update", yesterday.Add(19 * time.Hour).Format(time.RFC3339), "125.00"},
+	}
+	for _, tt := range tests {
+		body := `{"amount": "100", "from": "EUR", "to": "USD"`
+		if tt.date != "" {
+			body += `, "date": "` + tt.date + `"`
+		}
+		rec := serve(routes, http.MethodPost, "/v2/convert", body+"}", nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d (%s)", tt.name, rec.Code, rec.Body)
+			continue
+		}
+		var resp models.ConvertResponse
+		decodeBody(t, rec, &resp)
+		if resp.ConvertedAmount != tt.want {
+			t.Errorf("%s: converted amount = %s, want %s", tt.name, resp.ConvertedAmount, tt.want)
+		}
+		if (resp.AsOf != nil) != (tt.date != "") {
+			t.Errorf("%s: asOf = %v", tt.name, resp.AsOf)
+		}
+	}
+
+	rec := serve(routes, http.MethodPost, "/v2/convert", `{"amount": "100", "from": "EUR", "to": "USD", "date": "yesterday"}`, nil)
+	var resp models.ErrorResponse
+	decodeBody(t, rec, &resp)
+	if rec.Code != http.StatusBadRequest || resp.Code != "INVALID_DATE" {
+		t.Errorf("invalid date: status %d, code %s", rec.Code, resp.Code)
+	}
+}
+
+func TestRateHistoryEndpoint(t *testing.T) {
+	routes, yesterday := historyRoutes(t)
+	date := yesterday.Format("2006-01-02")
+
+	rec := serve(routes, http.MethodGet, "/v2/rates/history?from=eur&to=usd&start="+date+"&end="+date, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	var history models.RateHistoryResponse
+	decodeBody(t, rec, &history)
+	// the default in effect at the start of the day, then the update at 18:00
+	// which a plain end date still includes
+	if history.From != "EUR" || history.To != "USD" || len(history.Points) != 2 ||
+		history.Points[0].Rate != "1.18" || history.Points[0].Source != converter.SourceDefault ||
+		history.Points[1].Rate != "1.25" || !history.Points[1].EffectiveAt.Equal(yesterday.Add(18*time.Hour)) {
+		t.Errorf("history = %+v", history)
+	}
+
+	rec = serve(routes, http.MethodGet, "/v2/rates/history?from=EUR&to=USD&end="+yesterday.Add(12*time.Hour).Format(time.RFC3339), "", nil)
+	var upToNoon models.RateHistoryResponse
+	decodeBody(t, rec, &upToNoon)
+	if len(upToNoon.Points) != 1 || upToNoon.Points[0].Rate != "1.18" {
+		t.Errorf("history up to noon = %+v, want only the default", upToNoon.Points)
+	}
+
+	errorTests := []struct {
+		name, query, code string
+	}{
+		{"missing pair", "from=EUR", "INVALID_PAIR"},
+		{"invalid start", "from=EUR&to=USD&start=soon", "INVALID_DATE"},
+		{"end before start", "from=EUR&to=USD&start=" + date + "&end=" + yesterday.AddDate(0, 0, -1).Format("2006-01-02"), "INVALID_DATE"},
+	}
+	for _, tt := range errorTests {
+		rec := serve(routes, http.MethodGet, "/v2/rates/history?"+tt.query, "", nil)
+		var resp models.ErrorResponse
+		decodeBody(t, rec, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Code != tt.code {
+			t.Errorf("%s: status %d, code %s; want 400 %s", tt.name, rec.Code, resp.Code, tt.code)
+		}
+	}
+}
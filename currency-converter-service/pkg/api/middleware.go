@@ -1,32 +1,174 @@
 package api
 
 import (
-	"log"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/metrics"
+	"currency-converter-service/pkg/models"
+
+	"github.com/gorilla/mux"
 )
 
-// LoggingMiddleware logs HTTP requests
-func LoggingMiddleware(next http.Handler) http.Handler {
+// LoggingMiddleware writes one structured access log record per request with
+// its request ID, status, response size, latency and remote address
+func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("request_id", RequestID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
+
+// UnmatchedRoute is the route label of requests no route matches, such as
+// 404, 405 and preflight answers. A fixed value keeps arbitrary paths out of
+// the label values.
+const UnmatchedRoute = "unmatched"
+
+// routeKey is the context key of the route template recorded by recordRoute
+type routeKey struct{}
+
+// MetricsMiddleware records the count and latency of requests by route
+// template, method and status. Like the access log it wraps the whole
+// handler; the router reports the template it matched through recordRoute.
+func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			route := UnmatchedRoute
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+			m.ObserveRequest(route, r.Method, rec.status, time.Since(start))
+		})
+	}
+}
+
+// recordRoute passes the template of the matched route to MetricsMiddleware.
+// It runs as router middleware, the only place the match is known.
+func recordRoute(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+		route, ok := r.Context().Value(routeKey{}).(*string)
+		if current := mux.CurrentRoute(r); ok && current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				*route = template
+			}
+		}
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %v", r.Method, r.URL.Path, time.Since(start))
 	})
 }
 
-// CORSMiddleware adds CORS headers
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+// statusRecorder captures the status code and body size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
 
-		next.ServeHTTP(w, r)
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Hijack hands the connection over for WebSocket upgrades, which gorilla's
+// upgrader only finds through the http.Hijacker interface
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status = http.StatusSwitchingProtocols
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Authenticate resolves the caller from "Authorization: Bearer <key>" or
+// "X-API-Key: <key>" and stores the principal in the request context.
+// Requests with an unknown key are refused with 401. Requests without a key
+// get the anonymous principal holding anonymousRole, or no role if it is empty.
+func Authenticate(principals *auth.Principals, anonymousRole auth.Role) func(http.Handler) http.Handler {
+	anonymous := auth.Principal{Name: auth.Anonymous}
+	if anonymousRole != "" {
+		anonymous.Roles = []auth.Role{anonymousRole}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				key = bearer
+			}
+
+			principal := anonymous
+			if key != "" {
+				var ok bool
+				if principal, ok = principals.Authenticate(key); !ok {
+					writeAuthError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "The API key is not valid")
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireRole only lets requests through whose principal holds role or a role
+// that includes it. It answers 401 to anonymous callers and 403 to
+// authenticated callers without the role.
+func RequireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.PrincipalFrom(r.Context())
+			switch {
+			case principal.Has(role):
+				next.ServeHTTP(w, r)
+			case principal.Name == "" || principal.Name == auth.Anonymous:
+				writeAuthError(w, http.StatusUnauthorized, "UNAUTHORIZED", fmt.Sprintf("An API key with the %s role is required", role))
+			default:
+				writeAuthError(w, http.StatusForbidden, "FORBIDDEN", fmt.Sprintf("%s does not have the %s role", principal.Name, role))
+			}
+		})
+	}
+}
+
+func writeAuthError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Error: message,
+		Code:  code,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/metrics"
+)
+
+// TestEveryResponseIsLogged checks that responses the router never matches
+// still carry a request ID and get an access log record
+func TestEveryResponseIsLogged(t *testing.T) {
+	var logs bytes.Buffer
+	cors := DefaultCORSConfig()
+	cors.AllowedOrigins = []string{"https://app.example.com"}
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{
+		AnonymousRole: auth.RoleViewer,
+		CORS:          cors,
+		Logger:        slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+
+	tests := []struct {
+		method, path string
+		headers      map[string]string
+		status       int
+	}{
+		{http.MethodGet, "/v2/currencies", nil, http.StatusOK},
+		{http.MethodGet, "/nope", nil, http.StatusNotFound},
+		{http.MethodPut, "/v2/rates", nil, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v2/rates", map[string]string{"X-API-Key": "wrong"}, http.StatusUnauthorized},
+		{http.MethodOptions, "/v2/convert", map[string]string{
+			"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST",
+		}, http.StatusNoContent},
+		{http.MethodGet, "/v2/currencies", map[string]string{RequestIDHeader: "caller-id-1"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		rec := serve(routes, tt.method, tt.path, "", tt.headers)
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+			continue
+		}
+		id := rec.Header().Get(RequestIDHeader)
+		if id == "" || (tt.headers[RequestIDHeader] != "" && id != tt.headers[RequestIDHeader]) {
+			t.Errorf("%s %s: request ID = %q", tt.method, tt.path, id)
+		}
+
+		var record struct {
+			Msg       string `json:"msg"`
+			RequestID string `json:"request_id"`
+			Method    string `json:"method"`
+			Status    int    `json:"status"`
+		}
+		if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+			t.Errorf("%s %s: access log %q: %v", tt.method, tt.path, logs.String(), err)
+			continue
+		}
+		if record.Msg != "request" || record.RequestID != id || record.Method != tt.method || record.Status != tt.status {
+			t.Errorf("%s %s: access log = %+v, want request %s with status %d", tt.method, tt.path, record, id, tt.status)
+		}
+	}
+}
+
+// TestEveryResponseIsCounted checks that requests the router never matches
+// are counted under the fixed unmatched route rather than their path
+func TestEveryResponseIsCounted(t *testing.T) {
+	cors := DefaultCORSConfig()
+	cors.AllowedOrigins = []string{"https://app.example.com"}
+	m := metrics.New()
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer, CORS: cors, Metrics: m})
+
+	requests := []struct {
+		method, path string
+		headers      map[string]string
+	}{
+		{http.MethodGet, "/v2/currencies", nil},
+		{http.MethodGet, "/v2/rates/USD/EUR", nil},
+		{http.MethodGet, "/currencies", nil},
+		{http.MethodGet, "/nope/12345", nil},
+		{http.MethodPut, "/v2/rates", nil},
+		{http.MethodOptions, "/v2/convert", map[string]string{
+			"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST",
+		}},
+	}
+	for _, r := range requests {
+		serve(routes, r.method, r.path, "", r.headers)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`converter_http_requests_total{method="GET",route="/v2/currencies",status="200"} 1`,
+		`converter_http_requests_total{method="GET",route="/v2/rates/{from}/{to}",status="200"} 1`,
+		`converter_http_requests_total{method="GET",route="/v1/currencies",status="200"} 1`,
+		`converter_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`converter_http_requests_total{method="PUT",route="unmatched",status="405"} 1`,
+		`converter_http_requests_total{method="OPTIONS",route="unmatched",status="204"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+	if strings.Contains(body, "12345") {
+		t.Error("metrics label the path of an unmatched request")
+	}
+}
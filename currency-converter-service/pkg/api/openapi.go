@@ -0,0 +1,427 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/models"
+)
+
+// operation documents one route for the OpenAPI document. Path is relative
+// to the version prefix; operations without a Version are not versioned.
+type operation struct {
+	Version  string
+	Method   string
+	Path     string
+	Role     auth.Role
+	Summary  string
+	Query    []queryParam
+	Request  interface{}
+	Status   int
+	Response interface{}
+	// MediaType of the success response; empty means application/json
+	MediaType string
+	// Errors lists the error statuses besides 400 Bad Request, the
+	// authentication failures and, for versioned routes, 406 Not Acceptable
+	Errors []int
+	// Enabled reports whether a configuration serves the route; nil means
+	// it is always served
+	Enabled func(cfg Config) bool
+}
+
+// auditEnabled and metricsEnabled match the optional routes of SetupRoutes
+func auditEnabled(cfg Config) bool   { return cfg.Audit != nil }
+func metricsEnabled(cfg Config) bool { return cfg.Metrics != nil }
+
+// queryParam documents a query string parameter
+type queryParam struct {
+	Name        string
+	Description string
+}
+
+// statusResponse is the body of routes that only report success
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// operations describes every route of SetupRoutes; TestOperationsMatchRoutes
+// keeps the two in step. Operations of DefaultVersion are also listed at
+// their deprecated unversioned path.
+var operations = []operation{
+	{Version: APIv2, Method: "POST", Path: "/convert", Role: auth.RoleConverter,
+		Summary: "Convert an amount",
+		Request: models.ConvertRequest{}, Response: models.ConvertResponse{}},
+	{Version: APIv2, Method: "POST", Path: "/convert/batch", Role: auth.RoleConverter,
+		Summary: "Convert up to 1000 items against one rate snapshot",
+		Request: models.BatchConvertRequest{}, Response: models.BatchConvertResponse{}},
+	{Version: APIv2, Method: "POST", Path: "/convert/reverse", Role: auth.RoleConverter,
+		Summary: "Find the smallest source amount that yields a target amount after spread, fees and rounding",
+		Request: models.ReverseConvertRequest{}, Response: models.ReverseConvertResponse{}},
+	{Version: APIv2, Method: "GET", Path: "/currencies", Role: auth.RoleViewer,
+		Summary:  "List registered currencies",
+		Response: models.CurrenciesResponse{}},
+	{Version: APIv2, Method: "POST", Path: "/currencies", Role: auth.RoleRateAdmin,
+		Summary: "Register a currency",
+		Request: models.AddCurrencyRequest{}, Status: http.StatusCreated, Response: models.CurrencyResponse{}},
+	{Version: APIv2, Method: "DELETE", Path: "/currencies/{code}", Role: auth.RoleRateAdmin,
+		Summary:  "Unregister a currency and remove its rates",
+		Query:    []queryParam{{"reason", "Why, recorded in the audit log"}},
+		Response: models.RemoveCurrencyResponse{}, Errors: []int{http.StatusNotFound}},
+	{Version: APIv2, Method: "GET", Path: "/rates", Role: auth.RoleViewer,
+		Summary:  "Get the rate table",
+		Query:    []queryParam{{"base", "Only list rates from this currency"}},
+		Response: models.RatesResponse{}},
+	{Version: APIv2, Method: "POST", Path: "/rates", Role: auth.RoleRateAdmin,
+		Summary: "Set an exchange rate",
+		Request: models.SetRateRequest{}, Response: statusResponse{}},
+	{Version: APIv2, Method: "DELETE", Path: "/rates", Role: auth.RoleRateAdmin,
+		Summary: "Reset rates to the defaults: all, one base currency, or one pair",
+		Query: []queryParam{
+			{"from", "Only reset rates from this currency"},
+			{"to", "With from, only reset this pair"},
+			{"reason", "Why, recorded in the audit log"},
+		},
+		Response: models.ResetRatesResponse{}},
+	{Version: APIv2, Method: "GET", Path: "/rates/history", Role: auth.RoleViewer,
+		Summary: "Get the rate time series of a pair",
+		Query: []queryParam{
+			{"from", "Source currency (required)"},
+			{"to", "Target currency (required)"},
+			{"start", "Start of the range, YYYY-MM-DD or RFC 3339"},
+			{"end", "End of the range, YYYY-MM-DD or RFC 3339"},
+		},
+		Response: models.RateHistoryResponse{}},
+	{Version: APIv2, Method: "GET", Path: "/rates/consistency", Role: auth.RoleViewer,
+		Summary:  "List pairs whose rate and opposite rate are not inverses",
+		Query:    []queryParam{{"threshold", "Allowed deviation in percent (default 1)"}},
+		Response: models.InverseAuditResponse{}},
+	{Version: APIv2, Method: "GET", Path: "/rates/health", Role: auth.RoleViewer,
+		Summary:  "Check the rate table for arbitrage cycles and unreachable pairs",
+		Query:    []queryParam{{"tolerance", "Allowed cycle deviation in percent (default 0.5)"}},
+		Response: models.RateHealthResponse{}, Errors: []int{http.StatusServiceUnavailable}},
+	{Version: APIv2, Method: "GET", Path: "/rates/stream", Role: auth.RoleViewer,
+		Summary:  "Stream rate changes as Server-Sent Events, or as WebSocket messages when the request asks for an upgrade",
+		Query:    []queryParam{{"currencies", "Comma-separated currencies; only changes of pairs involving one of them are sent"}},
+		Response: models.RateEventResponse{}, MediaType: "text/event-stream"},
+	{Version: APIv2, Method: "GET", Path: "/rates/audit", Role: auth.RoleViewer,
+		Summary: "List recorded rate changes with who made them, when and why, oldest first",
+		Query: []queryParam{
+			{"from", "Only changes of pairs from this currency"},
+			{"to", "Only changes of pairs to this currency"},
+			{"currency", "Only changes of pairs involving this currency"},
+			{"principal", "Only changes made by this principal"},
+			{"operation", "Only changes made by this operation: set, reset, remove-currency, import, remove-source or rollback"},
+			{"start", "Start of the range, YYYY-MM-DD or RFC 3339"},
+			{"end", "End of the range, YYYY-MM-DD or RFC 3339"},
+			{"limit", "Return only the newest entries, 1 to 1000 (default 100)"},
+		},
+		Response: models.AuditLogResponse{}, Enabled: auditEnabled},
+	{Version: APIv2, Method: "POST", Path: "/rates/audit/{id}/rollback", Role: auth.RoleRateAdmin,
+		Summary: "Restore the pair of an audit entry to the rate the entry set",
+		Request: models.RollbackRequest{}, Response: models.RollbackResponse{},
+		Errors: []int{http.StatusNotFound, http.StatusConflict}, Enabled: auditEnabled},
+	{Version: APIv2, Method: "GET", Path: "/rates/{from}/{to}", Role: auth.RoleViewer,
+		Summary:  "Get one rate with its source and last update time",
+		Response: models.RateResponse{}, Errors: []int{http.StatusNotFound}},
+	{Method: "GET", Path: "/metrics", Role: auth.RoleViewer,
+		Summary: "Prometheus metrics", MediaType: "text/plain", Enabled: metricsEnabled},
+	{Method: "GET", Path: "/openapi.json", Role: auth.RoleViewer,
+		Summary: "This document"},
+
+	{Version: APIv1, Method: "POST", Path: "/convert", Role: auth.RoleConverter,
+		Summary: "Convert an amount (original contract with JSON numbers)",
+		Request: models.ConvertRequestV1{}, Response: models.ConvertResponseV1{}},
+	{Version: APIv1, Method: "GET", Path: "/currencies", Role: auth.RoleViewer,
+		Summary:  "List the codes of the supported currencies",
+		Response: models.CurrenciesResponseV1{}},
+	{Version: APIv1, Method: "POST", Path: "/rates", Role: auth.RoleRateAdmin,
+		Summary: "Set an exchange rate",
+		Request: models.SetRateRequestV1{}, Response: statusResponse{}},
+}
+
+// OpenAPIDocument builds the OpenAPI 3 document of the API as configured,
+// leaving out the routes the configuration disables. Schemas are derived from
+// the request and response models and their validate tags.
+func OpenAPIDocument(cfg Config) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range operations {
+		if op.Enabled != nil && !op.Enabled(cfg) {
+			continue
+		}
+		path := op.Path
+		if op.Version != "" {
+			path = "/" + op.Version + op.Path
+		}
+		addOperation(paths, schemas, path, op, false)
+		if op.Version == DefaultVersion {
+			addOperation(paths, schemas, op.Path, op, true)
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Currency Converter Service",
+			"version": LatestVersion,
+			"description": "/v2 exchanges amounts and rates as string-encoded decimals; /v1 keeps the original " +
+				"contract with JSON numbers. Unversioned paths are deprecated aliases of the version asked for " +
+				"in the Accept header (application/vnd.currency-converter.v1+json or .v2+json), v1 by default. " +
+				"Request bodies with unknown fields are rejected.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// addOperation documents an operation at a path. Deprecated operations are
+// the unversioned aliases.
+func addOperation(paths map[string]map[string]interface{}, schemas map[string]interface{}, path string, op operation, deprecated bool) {
+	doc := map[string]interface{}{
+		"summary":     op.Summary,
+		"description": "Requires the " + string(op.Role) + " role.",
+		"operationId": operationID(op.Method, path),
+		"security":    []map[string][]string{{"apiKey": {}}, {"bearer": {}}, {}},
+	}
+	if deprecated {
+		doc["deprecated"] = true
+		doc["description"] = "Deprecated alias of /" + op.Version + op.Path + ". " + doc["description"].(string)
+	}
+
+	var params []map[string]interface{}
+	for _, name := range pathParams(path) {
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]interface{}{
+			"name": q.Name, "in": "query", "description": q.Description,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		doc["parameters"] = params
+	}
+
+	if op.Request != nil {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(schemaFor(reflect.TypeOf(op.Request), schemas, true)),
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case op.MediaType != "":
+		media := map[string]interface{}{}
+		if op.Response != nil {
+			media["schema"] = schemaFor(reflect.TypeOf(op.Response), schemas, false)
+		}
+		success["content"] = map[string]interface{}{op.MediaType: media}
+	case op.Response != nil:
+		success["content"] = jsonContent(schemaFor(reflect.TypeOf(op.Response), schemas, false))
+	default:
+		success["content"] = jsonContent(map[string]interface{}{"type": "object"})
+	}
+
+	errorSchema := schemaFor(reflect.TypeOf(models.ErrorResponse{}), schemas, false)
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	codes := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden}
+	if op.Version != "" {
+		codes = append(codes, http.StatusNotAcceptable)
+	}
+	for _, code := range append(codes, op.Errors...) {
+		response := map[string]interface{}{"description": http.StatusText(code), "content": jsonContent(errorSchema)}
+		// the health check reports an unhealthy table in its normal body
+		if code == http.StatusServiceUnavailable && op.Response != nil {
+			response["content"] = success["content"]
+		}
+		responses[strconv.Itoa(code)] = response
+	}
+	doc["responses"] = responses
+
+	if paths[path] == nil {
+		paths[path] = map[string]interface{}{}
+	}
+	paths[path][strings.ToLower(op.Method)] = doc
+}
+
+// OpenAPIHandler serves the OpenAPI document of a configuration, built once
+func OpenAPIHandler(cfg Config) http.HandlerFunc {
+	body, err := json.Marshal(OpenAPIDocument(cfg))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// operationID derives an operation ID such as "postV2ConvertBatch"
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return strings.ContainsRune("/{}.", r) }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// pathParams lists the {name} parameters of a route path
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaFor returns the schema of a type. Named structs are added to schemas
+// and referenced; strict structs, those of request bodies, reject unknown
+// properties.
+func schemaFor(t reflect.Type, schemas map[string]interface{}, strict bool) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == decimalType:
+		return map[string]interface{}{"type": "string", "format": "decimal", "example": "12.50"}
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), schemas, strict)}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + schemaName(t)}
+		if _, ok := schemas[schemaName(t)]; ok {
+			return ref
+		}
+		schema := map[string]interface{}{"type": "object"}
+		schemas[schemaName(t)] = schema // registered first so recursive types terminate
+
+		properties := map[string]interface{}{}
+		var required []string
+		addProperties(t, schemas, strict, properties, &required)
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		if strict {
+			schema["additionalProperties"] = false
+		}
+		return ref
+	}
+	return map[string]interface{}{}
+}
+
+// addProperties adds the fields of a struct, flattening embedded structs
+func addProperties(t reflect.Type, schemas map[string]interface{}, strict bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addProperties(field.Type, schemas, strict, properties, required)
+			continue
+		}
+		name := jsonName(field)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		schema := schemaFor(field.Type, schemas, strict)
+		if rules := ruleSchema(field.Tag.Get("validate"), field.Type); len(rules) > 0 {
+			// keywords next to $ref are ignored in OpenAPI 3.0, so only inline schemas get them
+			if _, isRef := schema["$ref"]; !isRef {
+				for k, v := range rules {
+					schema[k] = v
+				}
+			}
+		}
+		properties[name] = schema
+		if hasRule(field.Tag.Get("validate"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// ruleSchema translates validate rules into schema keywords
+func ruleSchema(tag string, t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	keywords := map[string]interface{}{}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		n, _ := strconv.Atoi(param)
+		switch {
+		case name == "len" && t.Kind() == reflect.String:
+			keywords["minLength"], keywords["maxLength"] = n, n
+		case name == "alpha":
+			keywords["pattern"] = "^[A-Za-z]+$"
+		case name == "oneof":
+			keywords["enum"] = strings.Fields(param)
+		case name == "min" && t.Kind() == reflect.Slice:
+			keywords["minItems"] = n
+		case name == "max" && t.Kind() == reflect.Slice:
+			keywords["maxItems"] = n
+		case name == "min":
+			keywords["minimum"] = n
+		case name == "max":
+			keywords["maximum"] = n
+		case name == "gt" && t == decimalType:
+			keywords["description"] = "Must be greater than " + param + "; bare JSON numbers are also accepted."
+		}
+	}
+	return keywords
+}
+
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName names a struct's schema; unexported helper types get an
+// exported-looking name
+func schemaName(t reflect.Type) string {
+	return strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+}
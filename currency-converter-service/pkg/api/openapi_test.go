@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// pathPattern strips the patterns of path variables, {id:[0-9]+} becoming {id}
+var pathPattern = regexp.MustCompile(`\{(\w+):[^}]*\}`)
+
+// TestOperationsMatchRoutes walks the router and expects every route in the
+// OpenAPI document and nothing else, with and without the optional routes
+func TestOperationsMatchRoutes(t *testing.T) {
+	auditLog, err := audit.Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	configs := map[string]Config{
+		"minimal":  {},
+		"complete": {Audit: auditLog, Metrics: metrics.New()},
+	}
+	for name, cfg := range configs {
+		var routes []string
+		err := newRouter(converter.NewCurrencyConverter(), cfg).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			methods, err := route.GetMethods()
+			if err != nil {
+				return nil // a version prefix
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return err
+			}
+			for _, method := range methods {
+				routes = append(routes, strings.ToLower(method)+" "+pathPattern.ReplaceAllString(path, "{$1}"))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: Walk: %v", name, err)
+		}
+
+		// unversioned aliases are served by versionRouter through the routes
+		// of DefaultVersion, so they have to match one of those
+		var documented []string
+		for path, methods := range OpenAPIDocument(cfg)["paths"].(map[string]map[string]interface{}) {
+			for method, doc := range methods {
+				served := path
+				if doc.(map[string]interface{})["deprecated"] == true {
+					served = "/" + DefaultVersion + path
+				}
+				documented = append(documented, method+" "+served)
+			}
+		}
+
+		sort.Strings(routes)
+		documented = unique(documented)
+		if strings.Join(routes, "\n") != strings.Join(documented, "\n") {
+			t.Errorf("%s: routes\n%s\ndocumented as\n%s", name, strings.Join(routes, "\n"), strings.Join(documented, "\n"))
+		}
+	}
+}
+
+// unique sorts a list and removes duplicates
+func unique(list []string) []string {
+	sort.Strings(list)
+	var out []string
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func TestOpenAPILeavesOutDisabledRoutes(t *testing.T) {
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer})
+	rec := serve(routes, http.MethodGet, "/openapi.json", "", nil)
+	var doc struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	decodeBody(t, rec, &doc)
+	for _, path := range []string{"/v2/rates/audit", "/v2/rates/audit/{id}/rollback", "/metrics"} {
+		if _, ok := doc.Paths[path]; ok {
+			t.Errorf("%s is documented but not served", path)
+		}
+	}
+	if _, ok := doc.Paths["/v2/rates/health"]; !ok {
+		t.Error("/v2/rates/health is not documented")
+	}
+}
@@ -1,24 +1,116 @@
 package api
 
 import (
-	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
 	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/metrics"
+
+	"github.com/gorilla/mux"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes(conv converter.ICurrencyConverter) *mux.Router {
+// Config holds the settings of the HTTP API
+type Config struct {
+	// Principals maps API keys to their roles. Nil means no keys are accepted.
+	Principals *auth.Principals
+	// AnonymousRole is granted to requests without an API key; empty grants none
+	AnonymousRole auth.Role
+	// CORS is the cross-origin policy; see DefaultCORSConfig
+	CORS CORSConfig
+	// Logger receives the access log; nil uses slog.Default()
+	Logger *slog.Logger
+	// Metrics records HTTP traffic and is served at /metrics; nil disables both
+	Metrics *metrics.Metrics
+	// Audit records rate changes and is served at /rates/audit; nil disables both
+	Audit *audit.Log
+	// Sunset is announced on unversioned paths as the time they stop working;
+	// zero announces none
+	Sunset time.Time
+}
+
+// SetupRoutes configures all API routes. The API is served under /v1, the
+// original float contract, and /v2; unversioned paths are deprecated aliases
+// of the version negotiated from the Accept header. Reading requires
+// RoleViewer, converting RoleConverter and changing rates or currencies
+// RoleRateAdmin.
+func SetupRoutes(conv converter.ICurrencyConverter, cfg Config) http.Handler {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	r := newRouter(conv, cfg)
+
+	// CORS runs outside the router: preflight requests use OPTIONS, which no
+	// route matches. Request IDs, the access log and metrics cover every
+	// response, including 404, 405 and preflight answers.
+	var h http.Handler = versionRouter(CORSMiddleware(cfg.CORS)(r), cfg.Sunset)
+	if cfg.Metrics != nil {
+		h = MetricsMiddleware(cfg.Metrics)(h)
+	}
+	return RequestIDMiddleware(LoggingMiddleware(cfg.Logger)(h))
+}
+
+// newRouter registers the versioned routes and the ones outside the
+// versions; every route is documented in operations
+func newRouter(conv converter.ICurrencyConverter, cfg Config) *mux.Router {
 	handler := NewHandler(conv)
-	
+	handler.auditLog = cfg.Audit
+	if cfg.Principals == nil {
+		cfg.Principals = auth.NewPrincipals()
+	}
+
 	r := mux.NewRouter()
-	
-	// Apply middleware
-	r.Use(LoggingMiddleware)
-	r.Use(CORSMiddleware)
-	
-	// API routes
-	r.HandleFunc("/convert", handler.ConvertHandler).Methods("POST")
-	r.HandleFunc("/currencies", handler.CurrenciesHandler).Methods("GET")
-	r.HandleFunc("/rates", handler.SetRateHandler).Methods("POST")
-	
+
+	// Apply middleware to matched routes; see SetupRoutes for what wraps the router
+	if cfg.Metrics != nil {
+		r.Use(recordRoute)
+	}
+	r.Use(VersionMiddleware)
+	r.Use(Authenticate(cfg.Principals, cfg.AnonymousRole))
+
+	route := func(r *mux.Router, path, method string, role auth.Role, h http.HandlerFunc) {
+		r.Handle(path, RequireRole(role)(h)).Methods(method)
+	}
+
+	// /v1 keeps the original contract
+	v1 := r.PathPrefix("/" + APIv1).Subrouter()
+	route(v1, "/convert", "POST", auth.RoleConverter, handler.ConvertV1Handler)
+	route(v1, "/currencies", "GET", auth.RoleViewer, handler.CurrenciesV1Handler)
+	route(v1, "/rates", "POST", auth.RoleRateAdmin, handler.SetRateV1Handler)
+
+	v2 := r.PathPrefix("/" + APIv2).Subrouter()
+	route(v2, "/convert", "POST", auth.RoleConverter, handler.ConvertHandler)
+	route(v2, "/convert/batch", "POST", auth.RoleConverter, handler.BatchConvertHandler)
+	route(v2, "/convert/reverse", "POST", auth.RoleConverter, handler.ReverseConvertHandler)
+	route(v2, "/currencies", "GET", auth.RoleViewer, handler.CurrenciesHandler)
+	route(v2, "/currencies", "POST", auth.RoleRateAdmin, handler.AddCurrencyHandler)
+	route(v2, "/currencies/{code}", "DELETE", auth.RoleRateAdmin, handler.RemoveCurrencyHandler)
+	route(v2, "/rates", "GET", auth.RoleViewer, handler.RatesHandler)
+	route(v2, "/rates", "POST", auth.RoleRateAdmin, handler.SetRateHandler)
+	route(v2, "/rates", "DELETE", auth.RoleRateAdmin, handler.ResetRatesHandler)
+	route(v2, "/rates/history", "GET", auth.RoleViewer, handler.RateHistoryHandler)
+	route(v2, "/rates/consistency", "GET", auth.RoleViewer, handler.InverseAuditHandler)
+	route(v2, "/rates/health", "GET", auth.RoleViewer, handler.RateHealthHandler)
+	route(v2, "/rates/stream", "GET", auth.RoleViewer, handler.RateStreamHandler(cfg.CORS))
+	if cfg.Audit != nil {
+		route(v2, "/rates/audit", "GET", auth.RoleViewer, handler.AuditHandler)
+		route(v2, "/rates/audit/{id:[0-9]+}/rollback", "POST", auth.RoleRateAdmin, handler.RollbackHandler)
+	}
+	route(v2, "/rates/{from}/{to}", "GET", auth.RoleViewer, handler.RateHandler)
+
+	route(r, "/openapi.json", "GET", auth.RoleViewer, OpenAPIHandler(cfg))
+	if cfg.Metrics != nil {
+		route(r, "/metrics", "GET", auth.RoleViewer, cfg.Metrics.Handler().ServeHTTP)
+	}
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAuthError(w, http.StatusNotFound, "NOT_FOUND", "No route matches "+r.URL.Path)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAuthError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method+" is not supported on "+r.URL.Path)
+	})
 	return r
-}
\ No newline at end of file
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamBuffer is how many rate changes a stream client may fall behind
+	// before it is disconnected
+	streamBuffer = 256
+	// streamHeartbeat is the interval of SSE comments and WebSocket pings that
+	// keep idle connections open through proxies
+	streamHeartbeat = 15 * time.Second
+	// streamWriteTimeout bounds each WebSocket write
+	streamWriteTimeout = 10 * time.Second
+	// sseRetry is the reconnection delay suggested to EventSource clients
+	sseRetry = 3 * time.Second
+)
+
+// RateStreamHandler handles GET /rates/stream. It pushes every change of the
+// current rate table as a Server-Sent Event, or as a WebSocket text message
+// when the request asks for an upgrade. The optional currencies parameter
+// limits the stream to pairs involving one of the listed codes. A client that
+// falls more than streamBuffer events behind is disconnected and should
+// re-read GET /rates before streaming again. WebSocket upgrades are accepted
+// from the service's own origin and the origins the CORS policy allows.
+func (h *Handler) RateStreamHandler(cors CORSConfig) http.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || cors.allowsOrigin(origin) {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := map[string]bool{}
+		for _, code := range strings.Split(r.URL.Query().Get("currencies"), ",") {
+			if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+				filter[code] = true
+			}
+		}
+		matches := func(event converter.RateEvent) bool {
+			return len(filter) == 0 || filter[event.Pair.From] || filter[event.Pair.To]
+		}
+
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return // the upgrader has already replied
+			}
+			h.streamWebSocket(conn, matches)
+			return
+		}
+		h.streamEvents(w, r, matches)
+	}
+}
+
+// streamEvents writes rate changes as Server-Sent Events until the client
+// disconnects
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request, matches func(converter.RateEvent) bool) {
+	events, cancel := h.converter.SubscribeRates(streamBuffer)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n: streaming rate changes\n\n", sseRetry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	id := 0
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				data, _ := json.Marshal(models.ErrorResponse{Code: "STREAM_OVERFLOW",
+					Error: "rate stream fell behind; re-read the rates and reconnect"})
+				fmt.Fprintf(w, "event: overflow\ndata: %s\n\n", data)
+				rc.Flush()
+				return
+			}
+			if !matches(event) {
+				continue
+			}
+			data, err := json.Marshal(rateEventResponse(event))
+			if err != nil {
+				continue
+			}
+			id++
+			fmt.Fprintf(w, "id: %d\nevent: rate\ndata: %s\n\n", id, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// streamWebSocket writes rate changes as JSON text messages until the client
+// closes the connection or stops answering pings
+func (h *Handler) streamWebSocket(conn *websocket.Conn, matches func(converter.RateEvent) bool) {
+	defer conn.Close()
+	events, cancel := h.converter.SubscribeRates(streamBuffer)
+	defer cancel()
+
+	// The stream is one-way: read only to process control frames and notice
+	// the client going away
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "rate stream fell behind; re-read the rates and reconnect")
+				conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteTimeout))
+				return
+			}
+			if !matches(event) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(rateEventResponse(event)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// rateEventResponse converts a rate event for the API
+func rateEventResponse(event converter.RateEvent) models.RateEventResponse {
+	response := models.RateEventResponse{
+		From:      event.Pair.From,
+		To:        event.Pair.To,
+		Source:    event.Source,
+		Timestamp: event.At,
+	}
+	if !event.Previous.IsZero() {
+		response.Previous = event.Previous.String()
+	}
+	if !event.Current.IsZero() {
+		response.Current = event.Current.String()
+	}
+	return response
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"currency-converter-service/pkg/models"
+
+	"github.com/shopspring/decimal"
+)
+
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+// decode reads a single JSON object into v, rejecting unknown fields, and
+// checks its validate tags. It writes an error response and returns false if
+// the body is malformed or invalid.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		h.writeDecodeError(w, err)
+		return false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		h.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Request body must contain a single JSON object")
+		return false
+	}
+	if details := validate(v); len(details) > 0 {
+		h.writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Request validation failed",
+			Code:    "VALIDATION_FAILED",
+			Details: details,
+		})
+		return false
+	}
+	return true
+}
+
+// writeDecodeError reports a JSON decoding error, naming the offending field
+// where the decoder tells which one it is
+func (h *Handler) writeDecodeError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	var field, message string
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		field, message = typeErr.Field, "must be a "+typeErr.Type.String()
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, message = strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`), "is not a known field"
+	default:
+		h.writeError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON format")
+		return
+	}
+	h.writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+		Error:   "Invalid JSON format",
+		Code:    "INVALID_JSON",
+		Details: []models.FieldError{{Field: field, Message: message}},
+	})
+}
+
+// validate checks the validate tags of a request struct and returns one error
+// per failing field. Supported rules are required, omitempty, len, min, max,
+// gt, oneof and alpha. Elements of slices are not checked; handlers that
+// report errors per element validate them one by one.
+func validate(v interface{}) []models.FieldError {
+	var details []models.FieldError
+	validateStruct(reflect.Indirect(reflect.ValueOf(v)), "", &details)
+	return details
+}
+
+func validateStruct(v reflect.Value, prefix string, details *[]models.FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			validateStruct(value, prefix, details)
+			continue
+		}
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		path := prefix + name
+
+		if message := checkRules(value, field.Tag.Get("validate")); message != "" {
+			*details = append(*details, models.FieldError{Field: path, Message: message})
+		}
+	}
+}
+
+// jsonName returns the JSON name of a field, or "" if it is not encoded
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// checkRules applies a validate tag to a value and describes the first rule
+// it breaks, or returns "" if it passes
+func checkRules(value reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			if strings.Contains(tag, "required") {
+				return "is required"
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "omitempty":
+			if value.IsZero() {
+				return ""
+			}
+		case "required":
+			if value.IsZero() || (value.Type() == decimalType && value.Interface().(decimal.Decimal).IsZero()) {
+				return "is required"
+			}
+		case "len":
+			if n, _ := strconv.Atoi(param); size(value) != n {
+				return fmt.Sprintf("must be %d %s long", n, unit(value))
+			}
+		case "min":
+			if n, _ := strconv.ParseFloat(param, 64); measure(value) < n {
+				if value.Kind() == reflect.Slice && n == 1 {
+					return "must not be empty"
+				}
+				return fmt.Sprintf("must be at least %s%s", param, unitSuffix(value))
+			}
+		case "max":
+			if n, _ := strconv.ParseFloat(param, 64); measure(value) > n {
+				return fmt.Sprintf("must be at most %s%s", param, unitSuffix(value))
+			}
+		case "gt":
+			limit, _ := decimal.NewFromString(param)
+			if value.Type() == decimalType && !value.Interface().(decimal.Decimal).GreaterThan(limit) {
+				return "must be greater than " + param
+			}
+		case "oneof":
+			if !contains(strings.Fields(param), value.String()) {
+				return "must be one of " + strings.Join(strings.Fields(param), ", ")
+			}
+		case "alpha":
+			if strings.IndexFunc(value.String(), func(r rune) bool {
+				return (r < 'A' || r > 'Z') && (r < 'a' || r > 'z')
+			}) >= 0 {
+				return "must contain only letters"
+			}
+		}
+	}
+	return ""
+}
+
+// size is the length of a string in characters or of a slice in elements
+func size(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return len([]rune(value.String()))
+	}
+	if value.Kind() == reflect.Slice {
+		return value.Len()
+	}
+	return 0
+}
+
+// measure is the number min and max compare: a size or a numeric value
+func measure(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	return float64(size(value))
+}
+
+func unit(value reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		return "items"
+	}
+	return "characters"
+}
+
+func unitSuffix(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice:
+		return " " + unit(value)
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
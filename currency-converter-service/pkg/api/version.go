@@ -0,0 +1,128 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API versions, as they appear in paths
+const (
+	APIv1 = "v1"
+	APIv2 = "v2"
+)
+
+// LatestVersion is the current version of the API
+const LatestVersion = APIv2
+
+// DefaultVersion is the version unversioned paths serve when the Accept
+// header does not ask for another. They predate versioning and keep the
+// original contract, so they alias /v1.
+const DefaultVersion = APIv1
+
+// vendorPrefix starts the media types that select a version in the Accept
+// header, e.g. application/vnd.currency-converter.v1+json
+const vendorPrefix = "application/vnd.currency-converter."
+
+// UnversionedDeprecatedAt is when the unversioned paths were deprecated in
+// favour of /v1 and /v2
+var UnversionedDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// unversionedPaths are served without a version: they are not part of the
+// conversion API and never change with it
+var unversionedPaths = map[string]bool{
+	"/metrics":      true,
+	"/openapi.json": true,
+}
+
+// acceptedVersion returns the version an Accept header asks for, either as
+// application/vnd.currency-converter.vN+json or application/json;version=N.
+// It returns "" if the header names no version and ok=false if it names only
+// unsupported ones.
+func acceptedVersion(accept string) (version string, ok bool) {
+	named := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var candidate string
+		switch {
+		case strings.HasPrefix(mediaType, vendorPrefix) && strings.HasSuffix(mediaType, "+json"):
+			candidate = strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorPrefix), "+json")
+		case mediaType == "application/json" && params["version"] != "":
+			candidate = "v" + strings.TrimPrefix(params["version"], "v")
+		default:
+			continue
+		}
+		named = true
+		if candidate == APIv1 || candidate == APIv2 {
+			return candidate, true
+		}
+	}
+	return "", !named
+}
+
+// pathVersion returns the version prefix of a path, or ""
+func pathVersion(path string) string {
+	for _, version := range []string{APIv1, APIv2} {
+		if path == "/"+version || strings.HasPrefix(path, "/"+version+"/") {
+			return version
+		}
+	}
+	return ""
+}
+
+// versionRouter serves unversioned API paths as the version negotiated from
+// the Accept header, DefaultVersion by default, and marks them deprecated with
+// Deprecation, Sunset and Link headers pointing at the versioned path
+func versionRouter(next http.Handler, sunset time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pathVersion(r.URL.Path) != "" || unversionedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version, ok := acceptedVersion(r.Header.Get("Accept"))
+		if !ok || version == "" {
+			version = DefaultVersion // unsupported versions are refused by VersionMiddleware
+		}
+		successor := "/" + version + r.URL.Path
+
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(UnversionedDeprecatedAt.Unix(), 10))
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Add("Link", "<"+successor+`>; rel="successor-version"`)
+
+		r.URL.Path = successor
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = "/" + version + r.URL.RawPath
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// VersionMiddleware refuses requests whose Accept header asks for a version
+// other than the one of the path with 406 Not Acceptable, and reports the
+// version served in the API-Version header
+func VersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served := pathVersion(r.URL.Path)
+		if served == "" || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accepted, ok := acceptedVersion(r.Header.Get("Accept"))
+		if !ok || (accepted != "" && accepted != served) {
+			writeAuthError(w, http.StatusNotAcceptable, "UNSUPPORTED_VERSION",
+				"Accept asks for an API version this path does not serve (supported: "+APIv1+", "+APIv2+")")
+			return
+		}
+		w.Header().Set("API-Version", served)
+		next.ServeHTTP(w, r)
+	})
+}
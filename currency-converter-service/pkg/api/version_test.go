@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/models"
+)
+
+func TestVersionNegotiation(t *testing.T) {
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer, Sunset: sunset})
+
+	tests := []struct {
+		name, path, accept string
+		status             int
+		version            string // served version, or the error code on failure
+		deprecated         bool
+	}{
+		{"unversioned", "/currencies", "", http.StatusOK, APIv1, true},
+		{"unversioned plain json", "/currencies", "application/json", http.StatusOK, APIv1, true},
+		{"unversioned asking for v1", "/currencies", "application/vnd.currency-converter.v1+json", http.StatusOK, APIv1, true},
+		{"unversioned asking for v2", "/currencies", "application/vnd.currency-converter.v2+json", http.StatusOK, APIv2, true},
+		{"unversioned version parameter", "/currencies", "application/json; version=2", http.StatusOK, APIv2, true},
+		{"unversioned unknown version", "/currencies", "application/vnd.currency-converter.v3+json", http.StatusNotAcceptable, "UNSUPPORTED_VERSION", true},
+		{"unknown then supported", "/currencies", "application/vnd.currency-converter.v3+json, application/json; version=2", http.StatusOK, APIv2, true},
+		{"v1", "/v1/currencies", "", http.StatusOK, APIv1, false},
+		{"v2", "/v2/currencies", "application/vnd.currency-converter.v2+json", http.StatusOK, APIv2, false},
+		{"v2 asking for v1", "/v2/currencies", "application/vnd.currency-converter.v1+json", http.StatusNotAcceptable, "UNSUPPORTED_VERSION", false},
+		{"v1 asking for v2", "/v1/currencies", "application/json; version=2", http.StatusNotAcceptable, "UNSUPPORTED_VERSION", false},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{}
+		if tt.accept != "" {
+			headers["Accept"] = tt.accept
+		}
+		rec := serve(routes, http.MethodGet, tt.path, "", headers)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+
+		header := rec.Header()
+		if tt.deprecated {
+			if got := header.Get("Deprecation"); got != "@"+strconv.FormatInt(UnversionedDeprecatedAt.Unix(), 10) {
+				t.Errorf("%s: Deprecation = %q", tt.name, got)
+			}
+			if got := header.Get("Sunset"); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+				t.Errorf("%s: Sunset = %q", tt.name, got)
+			}
+		} else if header.Get("Deprecation") != "" || header.Get("Sunset") != "" || header.Get("Link") != "" {
+			t.Errorf("%s: versioned path marked deprecated: %v", tt.name, header)
+		}
+
+		if tt.status != http.StatusOK {
+			var resp models.ErrorResponse
+			decodeBody(t, rec, &resp)
+			if resp.Code != tt.version {
+				t.Errorf("%s: code = %s, want %s", tt.name, resp.Code, tt.version)
+			}
+			continue
+		}
+		if got := header.Get("API-Version"); got != tt.version {
+			t.Errorf("%s: API-Version = %q, want %s", tt.name, got, tt.version)
+		}
+		if want := `</` + tt.version + `/currencies>; rel="successor-version"`; tt.deprecated && header.Get("Link") != want {
+			t.Errorf("%s: Link = %q, want %q", tt.name, header.Get("Link"), want)
+		}
+
+		// v1 lists bare codes, v2 objects with the ISO 4217 metadata
+		var body struct {
+			Currencies []json.RawMessage `json:"currencies"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.Currencies) == 0 || (body.Currencies[0][0] == '"') != (tt.version == APIv1) {
+			t.Errorf("%s: body is not the %s contract: %s", tt.name, tt.version, rec.Body)
+		}
+	}
+}
+
+func TestUnversionedServiceRoutes(t *testing.T) {
+	routes := newTestRoutes(converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer, Sunset: time.Now().AddDate(1, 0, 0)})
+
+	rec := serve(routes, http.MethodGet, "/openapi.json", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	for _, name := range []string{"Deprecation", "Sunset", "Link", "API-Version"} {
+		if got := rec.Header().Get(name); got != "" {
+			t.Errorf("%s = %q on /openapi.json", name, got)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package audit keeps an append-only record of rate changes: who changed a
+// pair, from what to what, when, why and in which request.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+// Operations that change rates
+const (
+	OpSet            = "set"
+	OpReset          = "reset"
+	OpRemoveCurrency = "remove-currency"
+	OpImport         = "import"
+	OpRemoveSource   = "remove-source"
+	OpRollback       = "rollback"
+)
+
+// Entry records the change of one pair. Previous is empty for an added pair
+// and Current is empty for a removed one. RollbackOf is the ID of the entry a
+// rollback restored.
+type Entry struct {
+	ID         int64     `json:"id"`
+	At         time.Time `json:"at"`
+	Operation  string    `json:"operation"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Previous   string    `json:"previous,omitempty"`
+	Current    string    `json:"current,omitempty"`
+	Principal  string    `json:"principal"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	RollbackOf int64     `json:"rollbackOf,omitempty"`
+}
+
+// Change describes who made a set of rate changes and why
+type Change struct {
+	Operation  string
+	Principal  string
+	RequestID  string
+	Reason     string
+	RollbackOf int64
+}
+
+// Filter selects entries. Empty fields match everything; Currency matches
+// either side of a pair and Limit keeps only the newest entries.
+type Filter struct {
+	From      string
+	To        string
+	Currency  string
+	Principal string
+	Operation string
+	Start     time.Time
+	End       time.Time
+	Limit     int
+}
+
+func (f Filter) matches(e Entry) bool {
+	return (f.From == "" || f.From == e.From) &&
+		(f.To == "" || f.To == e.To) &&
+		(f.Currency == "" || f.Currency == e.From || f.Currency == e.To) &&
+		(f.Principal == "" || f.Principal == e.Principal) &&
+		(f.Operation == "" || f.Operation == e.Operation) &&
+		(f.Start.IsZero() || !e.At.Before(f.Start)) &&
+		(f.End.IsZero() || !e.At.After(f.End))
+}
+
+// Log is the audit log. Entries are kept in memory and, when the log was
+// opened with a path, appended to that file as JSON lines; they are never
+// changed or removed.
+type Log struct {
+	mu      sync.RWMutex
+	entries []Entry
+	file    *os.File
+}
+
+// Open opens the audit log. An empty path keeps the log in memory only;
+// otherwise the entries already in the file are loaded and new ones appended.
+func Open(path string) (*Log, error) {
+	l := &Log{}
+	if path == "" {
+		return l, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		l.entries = append(l.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.file = file
+	return l, nil
+}
+
+// Close closes the file of the log
+func (l *Log) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Apply makes a rate change through mutate and records the changes it
+// makes. mutate must change rates through the converter it is given, which
+// writes the entries before the change becomes visible: if they cannot be
+// written the change is discarded and the error returned, so no change is
+// ever in effect without being audited. The log stays locked throughout, so
+// entries are in the order the changes were made. On a nil log Apply only
+// runs mutate on conv.
+func (l *Log) Apply(conv converter.ICurrencyConverter, change Change, mutate func(converter.ICurrencyConverter) ([]converter.RateDiff, error)) ([]converter.RateDiff, error) {
+	diffs, _, err := l.apply(conv, change, mutate)
+	return diffs, err
+}
+
+func (l *Log) apply(conv converter.ICurrencyConverter, change Change, mutate func(converter.ICurrencyConverter) ([]converter.RateDiff, error)) ([]converter.RateDiff, []Entry, error) {
+	if l == nil {
+		diffs, err := mutate(conv)
+		return diffs, nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	diffs, err := mutate(conv.WithCommit(func(diffs []converter.RateDiff) error {
+		recorded, err := l.record(change, diffs)
+		entries = append(entries, recorded...)
+		return err
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+	return diffs, entries, nil
+}
+
+// record appends one entry per diff and returns them. The caller must hold
+// the lock.
+func (l *Log) record(change Change, diffs []converter.RateDiff) ([]Entry, error) {
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().UTC()
+	nextID := int64(len(l.entries)) + 1
+	if len(l.entries) > 0 {
+		nextID = l.entries[len(l.entries)-1].ID + 1
+	}
+
+	entries := make([]Entry, 0, len(diffs))
+	var lines []byte
+	for i, diff := range diffs {
+		entry := Entry{
+			ID:         nextID + int64(i),
+			At:         now,
+			Operation:  change.Operation,
+			From:       diff.Pair.From,
+			To:         diff.Pair.To,
+			Principal:  change.Principal,
+			RequestID:  change.RequestID,
+			Reason:     change.Reason,
+			RollbackOf: change.RollbackOf,
+		}
+		if !diff.Previous.IsZero() {
+			entry.Previous = diff.Previous.String()
+		}
+		if !diff.Current.IsZero() {
+			entry.Current = diff.Current.String()
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append(lines, line...), '\n')
+		entries = append(entries, entry)
+	}
+
+	// entries only count once they are on disk
+	if l.file != nil {
+		if _, err := l.file.Write(lines); err != nil {
+			return nil, fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	l.entries = append(l.entries, entries...)
+	return entries, nil
+}
+
+// Get returns the entry with an ID
+func (l *Log) Get(id int64) (Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, entry := range l.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Query returns the entries matching a filter, oldest first
+func (l *Log) Query(f Filter) []Entry {
+	f.From = strings.ToUpper(f.From)
+	f.To = strings.ToUpper(f.To)
+	f.Currency = strings.ToUpper(f.Currency)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var matched []Entry
+	for _, entry := range l.entries {
+		if f.matches(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return matched
+}
+
+// Rollback restores the pair of an entry to the rate the entry set, effective
+// now, and records the changes as a rollback of it. Entries that removed
+// their pair cannot be rolled back.
+func (l *Log) Rollback(conv converter.ICurrencyConverter, id int64, change Change) ([]Entry, error) {
+	entry, ok := l.Get(id)
+	if !ok {
+		return nil, converter.ConversionError{
+			Code:    "AUDIT_ENTRY_NOT_FOUND",
+			Message: fmt.Sprintf("audit entry %d does not exist", id),
+		}
+	}
+	if entry.Current == "" {
+		return nil, converter.ConversionError{
+			Code:    "ROLLBACK_UNSUPPORTED",
+			Message: fmt.Sprintf("audit entry %d removed %s/%s; set the rate again instead", id, entry.From, entry.To),
+			From:    entry.From,
+			To:      entry.To,
+		}
+	}
+	rate, err := decimal.NewFromString(entry.Current)
+	if err != nil {
+		return nil, err
+	}
+
+	change.Operation = OpRollback
+	change.RollbackOf = id
+	_, entries, err := l.apply(conv, change, func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.SetExchangeRateAt(entry.From, entry.To, rate, time.Now())
+	})
+	return entries, err
+}
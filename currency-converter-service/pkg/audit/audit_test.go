@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+func setRate(rate string) func(converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+	return func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.SetExchangeRateAt("USD", "EUR", decimal.RequireFromString(rate), time.Now())
+	}
+}
+
+func TestApplyRecordsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	conv := converter.NewCurrencyConverter()
+
+	change := Change{Operation: OpSet, Principal: "alice", RequestID: "req-1", Reason: "desk update"}
+	if _, err := log.Apply(conv, change, setRate("0.9")); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if _, err := log.Apply(conv, change, setRate("-1")); err == nil {
+		t.Fatal("Apply accepted a negative rate")
+	}
+	entries, err := log.Rollback(conv, 1, Change{Principal: "bob", Reason: "undo"})
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	// the rate stays the same but takes effect anew
+	if len(entries) != 1 || entries[0].Previous != "0.9" || entries[0].Current != "0.9" {
+		t.Errorf("rolling back to the current rate recorded %+v", entries)
+	}
+	log.Close()
+
+	log, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer log.Close()
+	got := log.Query(Filter{Principal: "alice"})
+	if len(got) != 1 {
+		t.Fatalf("entries by alice = %+v, want one", got)
+	}
+	want := Entry{ID: 1, At: got[0].At, Operation: OpSet, From: "USD", To: "EUR", Previous: "0.85", Current: "0.9",
+		Principal: "alice", RequestID: "req-1", Reason: "desk update"}
+	if got[0] != want {
+		t.Errorf("entry = %+v, want %+v", got[0], want)
+	}
+
+	if _, err := log.Apply(conv, Change{Operation: OpSet, Principal: "carol"}, setRate("0.95")); err != nil {
+		t.Fatalf("Apply after reopen: %v", err)
+	}
+	if entries, _ := log.Rollback(conv, 1, Change{Principal: "bob"}); len(entries) != 1 || entries[0].ID != 4 || entries[0].RollbackOf != 1 {
+		t.Errorf("rollback after reopen recorded %+v, want entry 4 rolling back 1", entries)
+	}
+}
+
+func TestApplyFailsWhenTheChangeCannotBeRecorded(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	log.Close()
+
+	conv := converter.NewCurrencyConverter()
+	events, cancel := conv.SubscribeRates(1)
+	defer cancel()
+	if _, err := log.Apply(conv, Change{Operation: OpSet, Principal: "alice"}, setRate("0.9")); err == nil {
+		t.Fatal("Apply succeeded without writing the audit log")
+	}
+	if entries := log.Query(Filter{}); len(entries) != 0 {
+		t.Errorf("unwritten entries are listed: %+v", entries)
+	}
+
+	// the change was discarded before anyone could see it
+	quote, err := conv.GetRateQuote("USD", "EUR")
+	if err != nil || !quote.Rate.Equal(decimal.RequireFromString("0.85")) || quote.Source != converter.SourceDefault {
+		t.Errorf("USD/EUR after the failed change = %+v, %v; want the default 0.85", quote, err)
+	}
+	if history := conv.GetRateHistory("USD", "EUR", time.Time{}, time.Time{}); len(history) != 1 {
+		t.Errorf("history after the failed change = %+v, want only the default", history)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("the failed change was published: %+v", event)
+	default:
+	}
+}
+
+func TestApplyOnNilLog(t *testing.T) {
+	var log *Log
+	diffs, err := log.Apply(converter.NewCurrencyConverter(), Change{Operation: OpSet}, setRate("0.9"))
+	if err != nil || len(diffs) != 1 {
+		t.Errorf("Apply on a nil log = %v, %v; want one diff", diffs, err)
+	}
+}
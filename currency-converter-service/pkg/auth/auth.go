@@ -0,0 +1,121 @@
+// Package auth maps API keys to principals and their roles.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Role grants access to a group of endpoints. Roles are ordered: every role
+// includes the access of the roles below it.
+type Role string
+
+// Supported roles, from least to most privileged
+const (
+	// RoleViewer may read currencies and rates
+	RoleViewer Role = "viewer"
+	// RoleConverter may also convert amounts
+	RoleConverter Role = "converter"
+	// RoleRateAdmin may also change rates and currencies
+	RoleRateAdmin Role = "rate-admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:    1,
+	RoleConverter: 2,
+	RoleRateAdmin: 3,
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(name))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (want %s, %s or %s)", name, RoleViewer, RoleConverter, RoleRateAdmin)
+	}
+	return role, nil
+}
+
+// Includes reports whether a holder of r has the access of other
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[other]
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name  string
+	Roles []Role
+}
+
+// Anonymous is the principal of requests without credentials
+const Anonymous = "anonymous"
+
+// Has reports whether the principal holds a role that includes role
+func (p Principal) Has(role Role) bool {
+	for _, held := range p.Roles {
+		if held.Includes(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Principals holds the known API keys. Keys are only kept as SHA-256 hashes.
+type Principals struct {
+	byKeyHash map[string]Principal
+}
+
+// NewPrincipals creates an empty set of principals
+func NewPrincipals() *Principals {
+	return &Principals{byKeyHash: make(map[string]Principal)}
+}
+
+// HashKey returns the hex SHA-256 hash of an API key, as stored in
+// principals files
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Add registers a principal under the hex SHA-256 hash of its API key
+func (p *Principals) Add(keyHash string, principal Principal) error {
+	keyHash = strings.ToLower(keyHash)
+	if decoded, err := hex.DecodeString(keyHash); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("principal %s: key hash must be a hex SHA-256 digest", principal.Name)
+	}
+	if len(principal.Roles) == 0 {
+		return fmt.Errorf("principal %s has no roles", principal.Name)
+	}
+	if existing, ok := p.byKeyHash[keyHash]; ok {
+		return fmt.Errorf("principals %s and %s share an API key", existing.Name, principal.Name)
+	}
+	p.byKeyHash[keyHash] = principal
+	return nil
+}
+
+// Authenticate returns the principal an API key belongs to
+func (p *Principals) Authenticate(key string) (Principal, bool) {
+	principal, ok := p.byKeyHash[HashKey(key)]
+	return principal, ok
+}
+
+// Len returns the number of registered API keys
+func (p *Principals) Len() int {
+	return len(p.byKeyHash)
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFrom returns the principal stored in a context
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,50 @@
+package auth
+
+import "testing"
+
+func TestRoleIncludes(t *testing.T) {
+	tests := []struct {
+		held, wanted Role
+		want         bool
+	}{
+		{RoleRateAdmin, RoleViewer, true},
+		{RoleRateAdmin, RoleConverter, true},
+		{RoleConverter, RoleViewer, true},
+		{RoleViewer, RoleConverter, false},
+		{RoleConverter, RoleRateAdmin, false},
+		{Role("root"), RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.held.Includes(tt.wanted); got != tt.want {
+			t.Errorf("%s.Includes(%s) = %v, want %v", tt.held, tt.wanted, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalsAdd(t *testing.T) {
+	principals := NewPrincipals()
+	if err := principals.Add(HashKey("k1"), Principal{Name: "ops", Roles: []Role{RoleRateAdmin}}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if err := principals.Add(HashKey("k2"), Principal{Name: "idle"}); err == nil {
+		t.Error("Add accepted a principal without roles")
+	}
+	if err := principals.Add(HashKey("k1"), Principal{Name: "copy", Roles: []Role{RoleViewer}}); err == nil {
+		t.Error("Add accepted a key that is already registered")
+	}
+	if err := principals.Add("not-a-hash", Principal{Name: "bad", Roles: []Role{RoleViewer}}); err == nil {
+		t.Error("Add accepted a malformed key hash")
+	}
+
+	principal, ok := principals.Authenticate("k1")
+	if !ok || principal.Name != "ops" || !principal.Has(RoleViewer) {
+		t.Errorf("Authenticate(k1) = %+v, %v", principal, ok)
+	}
+	if _, ok := principals.Authenticate("k2"); ok {
+		t.Error("Authenticate accepted an unknown key")
+	}
+	if principals.Len() != 1 {
+		t.Errorf("Len = %d, want 1", principals.Len())
+	}
+}
@@ -0,0 +1,94 @@
+// Package config loads service configuration files. Files ending in .yaml or
+// .yml are read as YAML, everything else as JSON.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// pricingFile is the on-disk layout of the pricing profiles file
+type pricingFile struct {
+	Default  string                 `json:"default,omitempty" yaml:"default,omitempty"`
+	Profiles map[string]profileFile `json:"profiles" yaml:"profiles"`
+}
+
+type profileFile struct {
+	MarkupPercent decimal.Decimal       `json:"markupPercent" yaml:"markupPercent"`
+	Quotes        []quoteFile           `json:"quotes,omitempty" yaml:"quotes,omitempty"`
+	Fees          converter.FeeSchedule `json:"fees" yaml:"fees"`
+}
+
+type quoteFile struct {
+	From string          `json:"from" yaml:"from"`
+	To   string          `json:"to" yaml:"to"`
+	Bid  decimal.Decimal `json:"bid" yaml:"bid"`
+	Ask  decimal.Decimal `json:"ask" yaml:"ask"`
+}
+
+// LoadPricingProfiles reads pricing profiles and the name of the default
+// profile from a file such as
+//
+//	default: retail
+//	profiles:
+//	  retail:
+//	    markupPercent: "1.5"
+//	    quotes:
+//	      - {from: EUR, to: USD, bid: "1.08", ask: "1.10"}
+//	    fees: {percent: "0.5", fixed: "2", currency: USD, min: "1", max: "50"}
+func LoadPricingProfiles(path string) (string, []converter.PricingProfile, error) {
+	var file pricingFile
+	if err := load(path, &file); err != nil {
+		return "", nil, err
+	}
+
+	var profiles []converter.PricingProfile
+	for name, p := range file.Profiles {
+		profile := converter.PricingProfile{
+			Name:          name,
+			MarkupPercent: p.MarkupPercent,
+			Quotes:        make(map[converter.Pair]converter.BidAsk),
+			Fees:          p.Fees,
+		}
+		for _, quote := range p.Quotes {
+			pair := converter.Pair{From: strings.ToUpper(quote.From), To: strings.ToUpper(quote.To)}
+			profile.Quotes[pair] = converter.BidAsk{Bid: quote.Bid, Ask: quote.Ask}
+		}
+		if err := profile.Validate(); err != nil {
+			return "", nil, fmt.Errorf("%s: %w", path, err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if _, ok := file.Profiles[file.Default]; file.Default != "" && !ok {
+		return "", nil, fmt.Errorf("%s: default profile %q is not defined", path, file.Default)
+	}
+	return file.Default, profiles, nil
+}
+
+// load decodes a JSON or YAML file into v
+func load(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, v)
+	default:
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+
+	"currency-converter-service/pkg/auth"
+)
+
+// principalsFile is the on-disk layout of the principals file
+type principalsFile struct {
+	Principals []principalFile `json:"principals" yaml:"principals"`
+}
+
+// principalFile is one principal. Key holds the API key in clear text;
+// KeySHA256 its hex SHA-256 hash, which keeps the key itself out of the file.
+type principalFile struct {
+	Name      string   `json:"name" yaml:"name"`
+	Key       string   `json:"key,omitempty" yaml:"key,omitempty"`
+	KeySHA256 string   `json:"keySha256,omitempty" yaml:"keySha256,omitempty"`
+	Roles     []string `json:"roles" yaml:"roles"`
+}
+
+// LoadPrincipals reads API keys and their roles from a file such as
+//
+//	principals:
+//	  - name: budget-app
+//	    key: "change-me"
+//	    roles: [converter]
+//	  - name: treasury
+//	    keySha256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+//	    roles: [rate-admin]
+func LoadPrincipals(path string) (*auth.Principals, error) {
+	var file principalsFile
+	if err := load(path, &file); err != nil {
+		return nil, err
+	}
+
+	principals := auth.NewPrincipals()
+	for _, p := range file.Principals {
+		if p.Name == "" {
+			return nil, fmt.Errorf("%s: every principal needs a name", path)
+		}
+		if (p.Key == "") == (p.KeySHA256 == "") {
+			return nil, fmt.Errorf("%s: principal %s needs exactly one of key and keySha256", path, p.Name)
+		}
+
+		principal := auth.Principal{Name: p.Name}
+		for _, name := range p.Roles {
+			role, err := auth.ParseRole(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: principal %s: %w", path, p.Name, err)
+			}
+			principal.Roles = append(principal.Roles, role)
+		}
+
+		hash := p.KeySHA256
+		if p.Key != "" {
+			hash = auth.HashKey(p.Key)
+		}
+		if err := principals.Add(hash, principal); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return principals, nil
+}
@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxBatchSize is the largest number of items accepted by ConvertBatch
+const MaxBatchSize = 1000
+
+// BatchItem is one conversion in a batch. ID is chosen by the caller and
+// echoed in the matching BatchResult.
+type BatchItem struct {
+	ID     string
+	Amount decimal.Decimal
+	From   string
+	To     string
+}
+
+// BatchResult holds either the result or the error of one batch item
+type BatchResult struct {
+	ID     string
+	Result *ConversionResult
+	Error  *ConversionError
+}
+
+// ConvertBatch converts every item against one rate snapshot, so all results
+// are consistent with each other even while rates are being updated. A failing
+// item does not affect the others. Results are returned in item order.
+func (c *CurrencyConverter) ConvertBatch(items []BatchItem, opts ...ConvertOption) ([]BatchResult, error) {
+	if len(items) == 0 || len(items) > MaxBatchSize {
+		return nil, ConversionError{
+			Code:    "INVALID_BATCH",
+			Message: fmt.Sprintf("a batch must contain between 1 and %d items", MaxBatchSize),
+		}
+	}
+
+	o := NewConvertOptions(opts...)
+	table := c.snapshot(o)
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		results[i].ID = item.ID
+
+		result, err := c.convert(table, o, item.Amount, item.From, item.To)
+		if err != nil {
+			convErr, ok := err.(ConversionError)
+			if !ok {
+				convErr = ConversionError{Code: "INTERNAL_ERROR", Message: err.Error()}
+			}
+			results[i].Error = &convErr
+			continue
+		}
+		o.annotate(result)
+		results[i].Result = result
+	}
+	return results, nil
+}
@@ -4,133 +4,675 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // CurrencyConverter implements the ICurrencyConverter interface
 type CurrencyConverter struct {
-	exchangeRates map[string]map[string]float64
+	rates         IExchangeRateProvider
+	currencies    *CurrencyRegistry
+	pivotCurrency string
+	rounding      RoundingMode
+
+	inversePolicy    InversePolicy
+	inverseTolerance decimal.Decimal
+
+	profiles       map[string]PricingProfile
+	defaultProfile string
+
+	events *rateHub
+	commit CommitFunc
+}
+
+// Option configures a CurrencyConverter
+type Option func(*CurrencyConverter)
+
+// WithPivotCurrency sets the currency used to triangulate pairs that have no
+// direct rate. An empty code disables pivoting and leaves only graph search.
+func WithPivotCurrency(code string) Option {
+	return func(c *CurrencyConverter) {
+		c.pivotCurrency = strings.ToUpper(code)
+	}
+}
+
+// WithRateProvider sets the store the converter reads and writes rates through.
+// The default is an in-memory provider seeded with DefaultExchangeRates.
+func WithRateProvider(provider IExchangeRateProvider) Option {
+	return func(c *CurrencyConverter) {
+		c.rates = provider
+	}
+}
+
+// WithCurrencyRegistry sets the registry of supported currencies. By default
+// the registry is restored from a provider that is a CurrencyStore, or holds
+// every currency found in the rate provider at startup.
+func WithCurrencyRegistry(registry *CurrencyRegistry) Option {
+	return func(c *CurrencyConverter) {
+		c.currencies = registry
+	}
+}
+
+// WithRoundingMode sets how converted amounts are rounded to the target
+// currency's minor units. The default is RoundHalfEven.
+func WithRoundingMode(mode RoundingMode) Option {
+	return func(c *CurrencyConverter) {
+		c.rounding = mode
+	}
+}
+
+// ConvertOption adjusts a single conversion
+type ConvertOption func(*ConvertOptions)
+
+// ConvertOptions is the resolved set of options for a conversion.
+// ICurrencyConverter implementations other than CurrencyConverter can obtain
+// it with NewConvertOptions to honour the options they are given.
+type ConvertOptions struct {
+	// AsOf selects the rates in effect at that time; zero means current rates
+	AsOf time.Time
+	// MaxAge rejects rates older than this with STALE_RATE; zero disables the check
+	MaxAge time.Duration
+	// Profile names the pricing profile; empty selects the default profile
+	Profile string
+	// Side selects the quoted rate when a profile applies; empty means SideBid
+	Side Side
+}
+
+// AsOf converts at the rates that were in effect at the given time instead
+// of the current ones
+func AsOf(at time.Time) ConvertOption {
+	return func(o *ConvertOptions) {
+		o.AsOf = at
+	}
+}
+
+// MaxAge fails the conversion with STALE_RATE if the rate it would use was
+// last updated longer ago than maxAge. Age is measured from the conversion's
+// AsOf time, or now. Rates that have never been updated since the defaults
+// have no known age and always count as stale.
+func MaxAge(maxAge time.Duration) ConvertOption {
+	return func(o *ConvertOptions) {
+		o.MaxAge = maxAge
+	}
+}
+
+// WithProfile converts with the spread and fees of a pricing profile
+func WithProfile(name string) ConvertOption {
+	return func(o *ConvertOptions) {
+		o.Profile = name
+	}
+}
+
+// WithSide selects the bid, ask or mid rate when a pricing profile applies
+func WithSide(side Side) ConvertOption {
+	return func(o *ConvertOptions) {
+		o.Side = side
+	}
+}
+
+// NewConvertOptions applies the given options
+func NewConvertOptions(opts ...ConvertOption) ConvertOptions {
+	var o ConvertOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
 // NewCurrencyConverter creates a new currency converter instance
-func NewCurrencyConverter() *CurrencyConverter {
-	// Deep copy of default rates
-	rates := make(map[string]map[string]float64)
+func NewCurrencyConverter(opts ...Option) *CurrencyConverter {
+	c := &CurrencyConverter{
+		pivotCurrency:    DefaultPivotCurrency,
+		rounding:         RoundHalfEven,
+		inversePolicy:    InverseIndependent,
+		inverseTolerance: DefaultInverseTolerance,
+		events:           newRateHub(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.rates == nil {
+		c.rates = NewMemoryRateProvider(DefaultRateTable())
+	}
+	if c.currencies == nil {
+		c.currencies = NewCurrencyRegistry(c.rates.Snapshot().Currencies()...)
+		if store, ok := c.rates.(CurrencyStore); ok {
+			if saved := store.SavedCurrencies(); saved != nil {
+				c.currencies = RestoreCurrencyRegistry(saved...)
+			}
+			c.currencies.persist = store.SaveCurrencies
+		}
+	}
+	// events are published under the provider's write lock, so subscribers
+	// receive them in the order the changes were made
+	c.rates.OnChange(c.publish)
+
+	return c
+}
+
+// WithCommit returns a view of the converter whose rate changes are passed to
+// commit before they become visible, and are discarded if it fails. The view
+// shares the rates, currencies and subscribers of the converter.
+func (c *CurrencyConverter) WithCommit(commit CommitFunc) ICurrencyConverter {
+	view := *c
+	view.commit = commit
+	return &view
+}
+
+// DefaultRateTable builds a decimal copy of DefaultExchangeRates
+func DefaultRateTable() RateTable {
+	rates := make(RateTable)
 
 	// loop through the nested map and extract K(from) and V(toRates which is  also a map)
 	for from, toRates := range DefaultExchangeRates {
-		rates[from] = make(map[string]float64)
 		//perform an inner loop to fill all the rate for a given currency
 		for to, rate := range toRates {
-			rates[from][to] = rate
+			rates.Set(from, to, RateEntry{Rate: decimal.NewFromFloat(rate), Source: SourceDefault})
 		}
 	}
 
-	return &CurrencyConverter{
-		exchangeRates: rates,
+	return rates
+}
+
+// Convert converts an amount from one currency to another. The result is
+// rounded to the minor units of the target currency.
+func (c *CurrencyConverter) Convert(amount decimal.Decimal, from, to string) (Money, error) {
+	result, err := c.convert(c.rates.Snapshot(), ConvertOptions{}, amount, from, to)
+	if err != nil {
+		return Money{}, err
 	}
+	return result.ConvertedAmount, nil
 }
 
-// Convert converts an amount from one currency to another
-func (c *CurrencyConverter) Convert(amount float64, from, to string) (float64, error) {
-	if amount <= 0 {
-		return 0, ConversionError{
-			Code:    "INVALID_AMOUNT",
-			Message: "amount must be greater than zero",
-			Amount:  amount,
-		}
+// ConvertAt converts an amount at the rates that were in effect at the given time
+func (c *CurrencyConverter) ConvertAt(amount decimal.Decimal, from, to string, at time.Time) (Money, error) {
+	result, err := c.convert(c.rates.SnapshotAt(at), ConvertOptions{AsOf: at}, amount, from, to)
+	if err != nil {
+		return Money{}, err
 	}
+	return result.ConvertedAmount, nil
+}
 
+// GetRate retrieves the exchange rate between two currencies, triangulating
+// through the pivot currency or the rate graph when there is no direct pair
+func (c *CurrencyConverter) GetRate(from, to string) (decimal.Decimal, error) {
 	from = strings.ToUpper(from)
 	to = strings.ToUpper(to)
 
-	// handle same currency
-	if from == to {
-		return amount, nil
+	rate, _, err := c.rates.Snapshot().findRoute(from, to, c.pivotCurrency)
+	return rate, err
+}
+
+// SetExchangeRate sets a custom exchange rate, effective now
+func (c *CurrencyConverter) SetExchangeRate(from, to string, rate decimal.Decimal) error {
+	_, err := c.SetExchangeRateAt(from, to, rate, time.Now())
+	return err
+}
+
+// SetExchangeRateAt records an exchange rate that took effect at the given
+// time. Depending on the inverse policy the opposite direction is derived
+// along with it, or the rate is rejected if it is inconsistent with it. It
+// returns the changes of the current table, which are none for a rate
+// backdated behind a newer one.
+func (c *CurrencyConverter) SetExchangeRateAt(from, to string, rate decimal.Decimal, effective time.Time) ([]RateDiff, error) {
+	if rate.Sign() <= 0 {
+		return nil, ConversionError{
+			Code:    "INVALID_RATE",
+			Message: "exchange rate must be greater than zero",
+		}
+	}
+
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	for _, code := range []string{from, to} {
+		if err := c.currencies.Ensure(code); err != nil {
+			return nil, err
+		}
 	}
 
-	rate, err := c.GetRate(from, to)
+	pair := Pair{From: from, To: to}
+	entry := RateEntry{Rate: rate, Source: SourceManual, UpdatedAt: effective}
+
+	// the policy is checked against the table the rate is recorded into, so
+	// no concurrent change to the opposite direction slips in between. A
+	// backdated rate only changes the current table if nothing newer exists.
+	diffs, err := c.rates.RecordRatesAt(effective, func(table RateTable) ([]RateChange, error) {
+		inverse, err := c.applyInversePolicy(table, pair, entry)
+		if err != nil {
+			return nil, err
+		}
+		changes := []RateChange{{
+			Pair:  pair,
+			Point: RatePoint{Rate: rate, EffectiveAt: effective, Source: SourceManual},
+		}}
+		if inverse != nil {
+			changes = append(changes, RateChange{
+				Pair:  Pair{From: to, To: from},
+				Point: RatePoint{Rate: inverse.Rate, EffectiveAt: effective, Source: inverse.Source},
+			})
+		}
+		return changes, nil
+	}, c.commit)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return diffs, nil
+}
 
-	return amount * rate, nil
+// GetRateHistory returns the recorded rates of a pair between start and end,
+// starting with the rate already in effect at start
+func (c *CurrencyConverter) GetRateHistory(from, to string, start, end time.Time) []RatePoint {
+	return c.rates.History(from, to, start, end)
 }
 
-// GetRate retrieves the exchange rate between two currencies
-func (c *CurrencyConverter) GetRate(from, to string) (float64, error) {
+// ListRates returns every stored rate, or only those of one base currency
+// when base is not empty
+func (c *CurrencyConverter) ListRates(base string) []RateQuote {
+	base = strings.ToUpper(base)
+	table := c.rates.Snapshot()
+
+	quotes := []RateQuote{}
+	for _, pair := range table.Pairs() {
+		if base != "" && pair.From != base {
+			continue
+		}
+		entry, _ := table.Entry(pair.From, pair.To)
+		quotes = append(quotes, RateQuote{
+			From:      pair.From,
+			To:        pair.To,
+			Rate:      entry.Rate,
+			Source:    entry.Source,
+			UpdatedAt: entry.UpdatedAt,
+			RouteKind: RouteDirect,
+			Route:     []string{pair.From, pair.To},
+		})
+	}
+	return quotes
+}
+
+// GetRateQuote returns the rate of a single pair with its source and update
+// time. Pairs without a stored rate are triangulated like in a conversion.
+func (c *CurrencyConverter) GetRateQuote(from, to string) (*RateQuote, error) {
 	from = strings.ToUpper(from)
 	to = strings.ToUpper(to)
+	table := c.rates.Snapshot()
 
-	if fromRates, exists := c.exchangeRates[from]; exists {
-		if rate, exists := fromRates[to]; exists {
-			return rate, nil
-		}
+	rate, route, err := table.findRoute(from, to, c.pivotCurrency)
+	if err != nil {
+		return nil, err
 	}
 
-	return 0, ConversionError{
-		Code:    "RATE_NOT_FOUND",
-		Message: fmt.Sprintf("exchange rate not found for %s to %s", from, to),
-		From:    from,
-		To:      to,
+	return &RateQuote{
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		Source:    table.routeSource(route),
+		UpdatedAt: table.routeUpdatedAt(route),
+		RouteKind: route.Kind,
+		Route:     route.Currencies,
+	}, nil
+}
+
+// GetSupportedCurrencies returns the codes of the registered currencies
+func (c *CurrencyConverter) GetSupportedCurrencies() []string {
+	var codes []string
+	for _, currency := range c.currencies.List() {
+		codes = append(codes, currency.Code)
 	}
+	return codes
 }
 
-// SetExchangeRate sets a custom exchange rate
-func (c *CurrencyConverter) SetExchangeRate(from, to string, rate float64) error {
-	if rate <= 0 {
-		return ConversionError{
-			Code:    "INVALID_RATE",
-			Message: "exchange rate must be greater than zero",
+// ListCurrencies returns the registered currencies with their ISO 4217
+// metadata and whether any rate is currently stored for them
+func (c *CurrencyConverter) ListCurrencies() []CurrencyInfo {
+	withRates := make(map[string]bool)
+	for _, code := range c.rates.Snapshot().Currencies() {
+		withRates[code] = true
+	}
+
+	var list []CurrencyInfo
+	for _, currency := range c.currencies.List() {
+		list = append(list, CurrencyInfo{Currency: currency, HasRates: withRates[currency.Code]})
+	}
+	return list
+}
+
+// money creates an amount in a currency at the minor units of the registry,
+// which may differ from ISO 4217 for currencies added through AddCurrency
+func (c *CurrencyConverter) money(amount decimal.Decimal, currency string) Money {
+	currency = strings.ToUpper(currency)
+	return newMoney(amount, currency, c.currencies.Scale(currency))
+}
+
+// AddCurrency registers a currency so rates can be set for it
+func (c *CurrencyConverter) AddCurrency(currency Currency) error {
+	return c.currencies.Add(currency)
+}
+
+// RemoveCurrency unregisters a currency and removes every rate that involves
+// it. It returns the pairs that were removed.
+func (c *CurrencyConverter) RemoveCurrency(code string) ([]RateDiff, error) {
+	code = strings.ToUpper(code)
+	if _, ok := c.currencies.Lookup(code); !ok {
+		return nil, ConversionError{
+			Code:    "UNKNOWN_CURRENCY",
+			Message: fmt.Sprintf("currency %s is not registered", code),
 		}
 	}
 
-	from = strings.ToUpper(from)
-	to = strings.ToUpper(to)
+	diffs, err := c.rates.UpdateRates(func(table RateTable) error {
+		for _, pair := range table.Pairs() {
+			if pair.From == code || pair.To == code {
+				table.Delete(pair.From, pair.To)
+			}
+		}
+		return nil
+	}, c.commit)
+	if err != nil {
+		return nil, err
+	}
 
-	if c.exchangeRates[from] == nil {
-		c.exchangeRates[from] = make(map[string]float64)
+	if _, err := c.currencies.Remove(code); err != nil {
+		return nil, err
 	}
-	c.exchangeRates[from][to] = rate
+	return diffs, nil
+}
 
-	return nil
+// ResetScope selects which rates ResetRates restores. An empty From resets
+// every pair, From alone resets all pairs of that base currency, and From
+// with To resets a single pair.
+type ResetScope struct {
+	From string
+	To   string
 }
 
-// SetRate implements IExchangeRateProvider interface
-func (c *CurrencyConverter) SetRate(from, to string, rate float64) error {
-	return c.SetExchangeRate(from, to, rate)
+// contains reports whether a pair falls within the scope
+func (s ResetScope) contains(from, to string) bool {
+	return (s.From == "" || s.From == from) && (s.To == "" || s.To == to)
 }
 
-// GetSupportedCurrencies returns the list of supported currencies
-func (c *CurrencyConverter) GetSupportedCurrencies() []string {
-	return SupportedCurrencies()
+// ResetRates restores the rates within scope to their default values. Pairs in
+// scope that have no default are removed. It returns the pairs that changed.
+func (c *CurrencyConverter) ResetRates(scope ResetScope) ([]RateDiff, error) {
+	scope.From = strings.ToUpper(scope.From)
+	scope.To = strings.ToUpper(scope.To)
+	if scope.From == "" && scope.To != "" {
+		return nil, ConversionError{
+			Code:    "INVALID_SCOPE",
+			Message: "a quote currency can only be reset together with its base currency",
+			To:      scope.To,
+		}
+	}
+
+	defaults := DefaultRateTable()
+	for _, pair := range defaults.Pairs() {
+		if scope.contains(pair.From, pair.To) {
+			c.currencies.Ensure(pair.From)
+			c.currencies.Ensure(pair.To)
+		}
+	}
+
+	now := time.Now()
+	diffs, err := c.rates.UpdateRates(func(table RateTable) error {
+		for _, pair := range table.Pairs() {
+			if _, ok := defaults.Entry(pair.From, pair.To); !ok && scope.contains(pair.From, pair.To) {
+				table.Delete(pair.From, pair.To)
+			}
+		}
+		for _, pair := range defaults.Pairs() {
+			if !scope.contains(pair.From, pair.To) {
+				continue
+			}
+			// leave pairs that already hold their default untouched
+			def, _ := defaults.Entry(pair.From, pair.To)
+			if current, ok := table.Entry(pair.From, pair.To); ok && current.Source == SourceDefault && current.Rate.Equal(def.Rate) {
+				continue
+			}
+			def.UpdatedAt = now
+			table.Set(pair.From, pair.To, def)
+		}
+		return nil
+	}, c.commit)
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
 }
 
-// ResetRates resets all exchange rates to default values
-func (c *CurrencyConverter) ResetRates() {
-	c.exchangeRates = make(map[string]map[string]float64)
-	for from, toRates := range DefaultExchangeRates {
-		c.exchangeRates[from] = make(map[string]float64)
-		for to, rate := range toRates {
-			c.exchangeRates[from][to] = rate
+// ImportRates merges a table of externally sourced rates into the current
+// table, registering their currencies. Every rate is validated before any is
+// applied. Entries take effect at their UpdatedAt, or now if it is zero. It
+// returns the pairs that changed.
+func (c *CurrencyConverter) ImportRates(rates RateTable) ([]RateDiff, error) {
+	for _, pair := range rates.Pairs() {
+		entry, _ := rates.Entry(pair.From, pair.To)
+		if entry.Rate.Sign() <= 0 {
+			return nil, ConversionError{
+				Code:    "INVALID_RATE",
+				Message: fmt.Sprintf("exchange rate for %s must be greater than zero", pair),
+				From:    pair.From,
+				To:      pair.To,
+			}
+		}
+		if entry.UpdatedAt.After(time.Now()) {
+			return nil, ConversionError{
+				Code:    "INVALID_DATE",
+				Message: fmt.Sprintf("exchange rate for %s takes effect in the future", pair),
+				From:    pair.From,
+				To:      pair.To,
+			}
+		}
+		for _, code := range []string{pair.From, pair.To} {
+			if err := c.currencies.Ensure(code); err != nil {
+				return nil, err
+			}
 		}
 	}
+
+	diffs, err := c.rates.UpdateRates(func(table RateTable) error {
+		for _, pair := range rates.Pairs() {
+			entry, _ := rates.Entry(pair.From, pair.To)
+			table.Set(pair.From, pair.To, entry)
+		}
+		// check against the table with the whole import applied, so an
+		// import carrying both directions is checked against itself
+		for _, pair := range rates.Pairs() {
+			entry, _ := rates.Entry(pair.From, pair.To)
+			inverse, err := c.applyInversePolicy(table, pair, entry)
+			if err != nil {
+				return err
+			}
+			if _, imported := rates.Entry(pair.To, pair.From); inverse != nil && !imported {
+				table.Set(pair.To, pair.From, *inverse)
+			}
+		}
+		return nil
+	}, c.commit)
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// RemoveRatesFrom removes every current rate recorded with the given source,
+// together with the inverses derived from them, and returns the pairs that
+// were removed
+func (c *CurrencyConverter) RemoveRatesFrom(source string) ([]RateDiff, error) {
+	diffs, err := c.rates.UpdateRates(func(table RateTable) error {
+		for _, pair := range table.Pairs() {
+			entry, ok := table.Entry(pair.From, pair.To)
+			if !ok || entry.Source != source {
+				continue
+			}
+			table.Delete(pair.From, pair.To)
+			if inverse, ok := table.Entry(pair.To, pair.From); ok && inverse.Source == SourceDerived {
+				table.Delete(pair.To, pair.From)
+			}
+		}
+		return nil
+	}, c.commit)
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
 }
 
 // ConvertWithResult returns a detailed conversion result
-func (c *CurrencyConverter) ConvertWithResult(amount float64, from, to string) (*ConversionResult, error) {
-	convertedAmount, err := c.Convert(amount, from, to)
+func (c *CurrencyConverter) ConvertWithResult(amount decimal.Decimal, from, to string, opts ...ConvertOption) (*ConversionResult, error) {
+	o := NewConvertOptions(opts...)
+	result, err := c.convert(c.snapshot(o), o, amount, from, to)
 	if err != nil {
 		return nil, err
 	}
+	o.annotate(result)
+	return result, nil
+}
+
+// annotate records the options that shaped a result
+func (o ConvertOptions) annotate(result *ConversionResult) {
+	if !o.AsOf.IsZero() {
+		asOf := o.AsOf
+		result.AsOf = &asOf
+	}
+}
+
+// side returns the selected side, SideBid unless another was chosen
+func (o ConvertOptions) side() Side {
+	if o.Side == "" {
+		return SideBid
+	}
+	return o.Side
+}
+
+// reference returns the time rate ages are measured from
+func (o ConvertOptions) reference() time.Time {
+	if o.AsOf.IsZero() {
+		return time.Now()
+	}
+	return o.AsOf
+}
+
+// snapshot returns the rate table a conversion with these options should use
+func (c *CurrencyConverter) snapshot(o ConvertOptions) RateTable {
+	if o.AsOf.IsZero() {
+		return c.rates.Snapshot()
+	}
+	return c.rates.SnapshotAt(o.AsOf)
+}
 
-	rate, _ := c.GetRate(from, to)
+// convert performs a conversion against a single rate snapshot so that the
+// rate, route and amount in the result are always consistent with each other
+func (c *CurrencyConverter) convert(table RateTable, o ConvertOptions, amount decimal.Decimal, from, to string) (*ConversionResult, error) {
+	if amount.Sign() <= 0 {
+		return nil, ConversionError{
+			Code:    "INVALID_AMOUNT",
+			Message: "amount must be greater than zero",
+			Amount:  amount.String(),
+		}
+	}
 
-	return &ConversionResult{
-		ConvertedAmount: convertedAmount,
-		OriginalAmount:  amount,
-		FromCurrency:    strings.ToUpper(from),
-		ToCurrency:      strings.ToUpper(to),
-		ExchangeRate:    rate,
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+
+	// handle same currency; without a rate lookup to fail, the code has to be
+	// checked against the registry
+	if from == to {
+		if _, ok := c.currencies.Lookup(from); !ok {
+			return nil, ConversionError{
+				Code:    "UNKNOWN_CURRENCY",
+				Message: fmt.Sprintf("currency %s is not registered", from),
+				Amount:  amount.String(),
+				From:    from,
+				To:      to,
+			}
+		}
+	}
+	rate, route := decimal.NewFromInt(1), Route{Kind: RouteDirect, Currencies: []string{from, to}}
+	var source string
+	var updatedAt time.Time
+	var age time.Duration
+	if from != to {
+		var err error
+		rate, route, err = table.findRoute(from, to, c.pivotCurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		source = table.routeSource(route)
+		updatedAt = table.routeUpdatedAt(route)
+		if !updatedAt.IsZero() {
+			age = o.reference().Sub(updatedAt)
+		}
+		if o.MaxAge > 0 && (updatedAt.IsZero() || age > o.MaxAge) {
+			message := fmt.Sprintf("exchange rate for %s to %s has no known update time", from, to)
+			if !updatedAt.IsZero() {
+				message = fmt.Sprintf("exchange rate for %s to %s is %s old, older than the allowed %s",
+					from, to, age.Round(time.Second), o.MaxAge)
+			}
+			return nil, ConversionError{
+				Code:    "STALE_RATE",
+				Message: message,
+				Amount:  amount.String(),
+				From:    from,
+				To:      to,
+			}
+		}
+	}
+
+	profile, err := c.profile(o.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := c.currencies.Scale(to)
+	applied, side := rate, SideMid
+	fee := decimal.Zero
+	if profile != nil {
+		side = o.side()
+		if from != to {
+			applied = profile.appliedRate(rate, from, to, side)
+		}
+	}
+	converted := NewMoney(amount.Mul(applied), to).RoundTo(scale, c.rounding)
+
+	if profile != nil {
+		feeRate := decimal.NewFromInt(1)
+		if feeCurrency := strings.ToUpper(profile.Fees.Currency); feeCurrency != "" && feeCurrency != to {
+			if feeRate, _, err = table.findRoute(feeCurrency, to, c.pivotCurrency); err != nil {
+				return nil, err
+			}
+		}
+		fee = roundDecimal(profile.Fees.fee(converted.Amount, feeRate), scale, c.rounding)
+		if fee.GreaterThanOrEqual(converted.Amount) {
+			return nil, ConversionError{
+				Code:    "FEE_EXCEEDS_AMOUNT",
+				Message: fmt.Sprintf("the fee of %s %s leaves nothing of the converted amount", fee.StringFixed(scale), to),
+				Amount:  amount.String(),
+				From:    from,
+				To:      to,
+			}
+		}
+	}
+
+	result := &ConversionResult{
+		ConvertedAmount: converted,
+		OriginalAmount:  c.money(amount, from),
+		FromCurrency:    from,
+		ToCurrency:      to,
+		ExchangeRate:    applied,
+		MidRate:         rate,
+		Side:            side,
+		Fee:             Money{Amount: fee, Currency: to, Scale: scale},
+		NetAmount:       Money{Amount: converted.Amount.Sub(fee), Currency: to, Scale: scale},
+		RouteKind:       route.Kind,
+		Route:           route.Currencies,
+		RateSource:      source,
+		RateUpdatedAt:   updatedAt,
+		RateAge:         age,
 		Timestamp:       time.Now(),
-	}, nil
+	}
+	if profile != nil {
+		result.Profile = profile.Name
+	}
+	return result, nil
 }
@@ -0,0 +1,216 @@
+package converter
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed data/iso4217.json
+var iso4217Data []byte
+
+// Currency describes a currency by its ISO 4217 metadata
+type Currency struct {
+	Code        string `json:"code" yaml:"code"`
+	NumericCode string `json:"numericCode,omitempty" yaml:"numericCode,omitempty"`
+	Name        string `json:"name" yaml:"name"`
+	MinorUnits  int32  `json:"minorUnits" yaml:"minorUnits"`
+	Symbol      string `json:"symbol,omitempty" yaml:"symbol,omitempty"`
+}
+
+// CurrencyInfo is a registered currency together with whether the rate table
+// currently holds any rate for it
+type CurrencyInfo struct {
+	Currency
+	HasRates bool `json:"hasRates"`
+}
+
+// isoCurrencies is the embedded ISO 4217 dataset keyed by code
+var isoCurrencies = loadISO4217()
+
+func loadISO4217() map[string]Currency {
+	var list []Currency
+	if err := json.Unmarshal(iso4217Data, &list); err != nil {
+		panic(fmt.Sprintf("converter: invalid embedded ISO 4217 dataset: %v", err))
+	}
+
+	currencies := make(map[string]Currency, len(list))
+	for _, currency := range list {
+		currencies[currency.Code] = currency
+	}
+	return currencies
+}
+
+// LookupISO4217 returns the ISO 4217 metadata of a currency code
+func LookupISO4217(code string) (Currency, bool) {
+	currency, ok := isoCurrencies[strings.ToUpper(code)]
+	return currency, ok
+}
+
+// CurrencyRegistry holds the currencies the converter supports. It is safe
+// for concurrent use.
+type CurrencyRegistry struct {
+	mu         sync.RWMutex
+	currencies map[string]Currency
+	// persist saves every change before it is made; see CurrencyStore
+	persist func(currencies []Currency) error
+}
+
+// NewCurrencyRegistry creates a registry holding the given codes. Codes found
+// in the ISO 4217 dataset get its metadata; others are registered with the
+// code as name and DefaultMinorUnits.
+func NewCurrencyRegistry(codes ...string) *CurrencyRegistry {
+	r := &CurrencyRegistry{currencies: make(map[string]Currency)}
+	for _, code := range codes {
+		code = strings.ToUpper(code)
+		currency, ok := LookupISO4217(code)
+		if !ok {
+			currency = Currency{Code: code, Name: code, MinorUnits: DefaultMinorUnits}
+		}
+		r.currencies[code] = currency
+	}
+	return r
+}
+
+// RestoreCurrencyRegistry creates a registry holding exactly the given
+// currencies, as previously saved to a CurrencyStore
+func RestoreCurrencyRegistry(currencies ...Currency) *CurrencyRegistry {
+	r := &CurrencyRegistry{currencies: make(map[string]Currency, len(currencies))}
+	for _, currency := range currencies {
+		r.currencies[strings.ToUpper(currency.Code)] = currency
+	}
+	return r
+}
+
+// Lookup returns a registered currency
+func (r *CurrencyRegistry) Lookup(code string) (Currency, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	currency, ok := r.currencies[strings.ToUpper(code)]
+	return currency, ok
+}
+
+// List returns every registered currency, sorted by code
+func (r *CurrencyRegistry) List() []Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedCurrencies(r.currencies)
+}
+
+func sortedCurrencies(currencies map[string]Currency) []Currency {
+	list := make([]Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		list = append(list, currency)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// persistLocked saves the registry as it is once code is set to currency, or
+// removed if currency is nil. The caller must hold the lock.
+func (r *CurrencyRegistry) persistLocked(code string, currency *Currency) error {
+	if r.persist == nil {
+		return nil
+	}
+	next := make(map[string]Currency, len(r.currencies)+1)
+	for existing, c := range r.currencies {
+		next[existing] = c
+	}
+	if currency == nil {
+		delete(next, code)
+	} else {
+		next[code] = *currency
+	}
+	return r.persist(sortedCurrencies(next))
+}
+
+// Add registers a currency, replacing any existing entry with the same code
+func (r *CurrencyRegistry) Add(currency Currency) error {
+	currency.Code = strings.ToUpper(currency.Code)
+	if !isCurrencyCode(currency.Code) {
+		return ConversionError{
+			Code:    "INVALID_CURRENCY",
+			Message: fmt.Sprintf("currency code %q must be three letters", currency.Code),
+		}
+	}
+	if currency.MinorUnits < 0 || currency.MinorUnits > 8 {
+		return ConversionError{
+			Code:    "INVALID_CURRENCY",
+			Message: "minor units must be between 0 and 8",
+		}
+	}
+	if currency.Name == "" {
+		currency.Name = currency.Code
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.persistLocked(currency.Code, &currency); err != nil {
+		return err
+	}
+	r.currencies[currency.Code] = currency
+	return nil
+}
+
+// Remove unregisters a currency and reports whether it was registered
+func (r *CurrencyRegistry) Remove(code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code = strings.ToUpper(code)
+	if _, ok := r.currencies[code]; !ok {
+		return false, nil
+	}
+	if err := r.persistLocked(code, nil); err != nil {
+		return false, err
+	}
+	delete(r.currencies, code)
+	return true, nil
+}
+
+// Ensure registers a currency from the ISO 4217 dataset if it is not
+// registered yet. Codes outside the dataset must be added explicitly first.
+func (r *CurrencyRegistry) Ensure(code string) error {
+	code = strings.ToUpper(code)
+	if _, ok := r.Lookup(code); ok {
+		return nil
+	}
+
+	currency, ok := LookupISO4217(code)
+	if !ok {
+		return ConversionError{
+			Code:    "UNKNOWN_CURRENCY",
+			Message: fmt.Sprintf("currency %s is not an ISO 4217 code and has not been registered", code),
+		}
+	}
+	return r.Add(currency)
+}
+
+// Scale returns the minor units of a registered currency, falling back to
+// the ISO 4217 dataset and then DefaultMinorUnits
+func (r *CurrencyRegistry) Scale(code string) int32 {
+	if currency, ok := r.Lookup(code); ok {
+		return currency.MinorUnits
+	}
+	return CurrencyScale(code)
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, ch := range code {
+		if ch < 'A' || ch > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRegisteredMinorUnitsApplyToBothAmounts(t *testing.T) {
+	conv := NewCurrencyConverter()
+	if err := conv.AddCurrency(Currency{Code: "xab", Name: "Test unit", MinorUnits: 4}); err != nil {
+		t.Fatalf("AddCurrency: %v", err)
+	}
+	if err := conv.SetExchangeRate("XAB", "USD", decimal.RequireFromString("2")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+	if err := conv.SetExchangeRate("USD", "XAB", decimal.RequireFromString("0.5")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+
+	result, err := conv.ConvertWithResult(decimal.NewFromInt(1), "XAB", "USD")
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if got := result.OriginalAmount.AmountString(); got != "1.0000" {
+		t.Errorf("original amount = %s, want 1.0000", got)
+	}
+
+	result, err = conv.ConvertWithResult(decimal.RequireFromString("1.23"), "USD", "XAB")
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if got := result.ConvertedAmount.AmountString(); got != "0.6150" {
+		t.Errorf("converted amount = %s, want 0.6150", got)
+	}
+	if got := result.OriginalAmount.AmountString(); got != "1.23" {
+		t.Errorf("original amount = %s, want 1.23", got)
+	}
+}
+
+func TestCurrencyRegistryAdd(t *testing.T) {
+	registry := NewCurrencyRegistry("USD")
+	for _, currency := range []Currency{{Code: "US"}, {Code: "U5D"}, {Code: "XAB", MinorUnits: 9}} {
+		if err := registry.Add(currency); err == nil {
+			t.Errorf("Add(%+v) succeeded, want INVALID_CURRENCY", currency)
+		}
+	}
+	if err := registry.Ensure("ZZZ"); err == nil {
+		t.Error("Ensure(ZZZ) succeeded for a code outside ISO 4217")
+	}
+	if err := registry.Ensure("kwd"); err != nil {
+		t.Fatalf("Ensure(KWD): %v", err)
+	}
+	if got := registry.Scale("KWD"); got != 3 {
+		t.Errorf("Scale(KWD) = %d, want 3", got)
+	}
+	if removed, _ := registry.Remove("usd"); !removed {
+		t.Error("Remove(usd) did not remove USD")
+	}
+	if removed, _ := registry.Remove("USD"); removed {
+		t.Error("Remove(USD) succeeded a second time")
+	}
+}
+
+func TestSameCurrencyConversionChecksTheRegistry(t *testing.T) {
+	conv := NewCurrencyConverter()
+
+	result, err := conv.ConvertWithResult(decimal.NewFromInt(5), "usd", "USD")
+	if err != nil || result.ConvertedAmount.String() != "5.00 USD" {
+		t.Errorf("USD to USD = %+v, %v; want 5.00 USD", result, err)
+	}
+	for _, code := range []string{"QQQ", "CHF"} {
+		_, err := conv.Convert(decimal.NewFromInt(5), code, code)
+		if convErr, ok := err.(ConversionError); !ok || convErr.Code != "UNKNOWN_CURRENCY" {
+			t.Errorf("%s to %s: err = %v, want UNKNOWN_CURRENCY", code, code, err)
+		}
+	}
+}
+
+// memoryCurrencyStore is a rate provider that keeps the currency registry
+type memoryCurrencyStore struct {
+	*MemoryRateProvider
+	saved []Currency
+	fail  bool
+}
+
+func (s *memoryCurrencyStore) SavedCurrencies() []Currency { return s.saved }
+
+func (s *memoryCurrencyStore) SaveCurrencies(currencies []Currency) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	s.saved = currencies
+	return nil
+}
+
+func TestRegistryIsRestoredFromACurrencyStore(t *testing.T) {
+	store := &memoryCurrencyStore{MemoryRateProvider: NewMemoryRateProvider(DefaultRateTable())}
+	conv := NewCurrencyConverter(WithRateProvider(store))
+	if err := conv.AddCurrency(Currency{Code: "XAB", Name: "Test unit", MinorUnits: 4}); err != nil {
+		t.Fatalf("AddCurrency: %v", err)
+	}
+	if _, err := conv.RemoveCurrency("JPY"); err != nil {
+		t.Fatalf("RemoveCurrency: %v", err)
+	}
+
+	store.fail = true
+	if err := conv.AddCurrency(Currency{Code: "XBB", Name: "Unsaved"}); err == nil {
+		t.Error("AddCurrency succeeded although the registry could not be saved")
+	}
+	store.fail = false
+
+	restored := NewCurrencyConverter(WithRateProvider(store))
+	codes := restored.GetSupportedCurrencies()
+	if strings.Join(codes, ",") != "AUD,CAD,EUR,GBP,USD,XAB" {
+		t.Errorf("restored currencies = %v", codes)
+	}
+	if scale := restored.currencies.Scale("XAB"); scale != 4 {
+		t.Errorf("restored XAB minor units = %d, want 4", scale)
+	}
+}
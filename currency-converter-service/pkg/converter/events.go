@@ -0,0 +1,114 @@
+package converter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RateEvent describes a change of the current rate table. Previous is zero
+// for an added pair and Current is zero for a removed one.
+type RateEvent struct {
+	Pair     Pair
+	Previous decimal.Decimal
+	Current  decimal.Decimal
+	// Source is the source of the new entry; it is empty for a removal
+	Source string
+	// At is when the new entry took effect, or when the pair was removed
+	At time.Time
+}
+
+// Removed reports whether the event removed its pair
+func (e RateEvent) Removed() bool {
+	return e.Current.IsZero()
+}
+
+// rateHub fans rate events out to subscribers. A subscriber whose buffer is
+// full is dropped and its channel closed, so a slow consumer never blocks a
+// rate update; it has to subscribe again and re-read the table.
+type rateHub struct {
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]chan RateEvent
+}
+
+func newRateHub() *rateHub {
+	return &rateHub{subscribers: make(map[int]chan RateEvent)}
+}
+
+// subscribe registers a subscriber; cancel unregisters it and closes its
+// channel, and may be called more than once
+func (h *rateHub) subscribe(buffer int) (<-chan RateEvent, func()) {
+	if buffer < 1 {
+		buffer = 1
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	ch := make(chan RateEvent, buffer)
+	h.subscribers[id] = ch
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if ch, ok := h.subscribers[id]; ok {
+			delete(h.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// publish delivers events to every subscriber without blocking
+func (h *rateHub) publish(events []RateEvent) {
+	if len(events) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, ch := range h.subscribers {
+	deliver:
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				delete(h.subscribers, id)
+				close(ch)
+				break deliver
+			}
+		}
+	}
+}
+
+// SubscribeRates returns a channel receiving every change of the current rate
+// table and a function that ends the subscription. The channel buffers up to
+// buffer events; a subscriber that falls further behind is dropped and its
+// channel closed.
+func (c *CurrencyConverter) SubscribeRates(buffer int) (<-chan RateEvent, func()) {
+	return c.events.subscribe(buffer)
+}
+
+// publish announces the diffs of a rate update to the subscribers, with the
+// source and effective time of the entries now in the table. The provider
+// calls it under its write lock.
+func (c *CurrencyConverter) publish(diffs []RateDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	table := c.rates.Snapshot()
+	now := time.Now()
+	events := make([]RateEvent, 0, len(diffs))
+	for _, diff := range diffs {
+		event := RateEvent{Pair: diff.Pair, Previous: diff.Previous, Current: diff.Current, At: now}
+		if entry, ok := table.Entry(diff.Pair.From, diff.Pair.To); ok {
+			event.Source = entry.Source
+			if !entry.UpdatedAt.IsZero() {
+				event.At = entry.UpdatedAt
+			}
+		}
+		events = append(events, event)
+	}
+	c.events.publish(events)
+}
@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func slowPersist(RateHistory, []RateChange) error {
+	time.Sleep(50 * time.Microsecond)
+	return nil
+}
+
+// TestConcurrentSetsPublishEachChangeOnce checks that every rate set by
+// concurrent writers is published exactly once, in the order it was written
+func TestConcurrentSetsPublishEachChangeOnce(t *testing.T) {
+	provider := RestoreMemoryRateProvider(SeedChanges(DefaultRateTable()), slowPersist)
+	conv := NewCurrencyConverter(WithRateProvider(provider))
+
+	quotes := []string{"USD", "GBP", "JPY", "CHF"}
+	const sets = 100
+	events, cancel := conv.SubscribeRates(len(quotes) * sets * 2)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, quote := range quotes {
+		wg.Add(1)
+		go func(quote string) {
+			defer wg.Done()
+			for i := 1; i <= sets; i++ {
+				if err := conv.SetExchangeRate("EUR", quote, decimal.NewFromInt(int64(i))); err != nil {
+					t.Errorf("SetExchangeRate: %v", err)
+					return
+				}
+			}
+		}(quote)
+	}
+	wg.Wait()
+	cancel()
+
+	last := map[string]int64{}
+	for event := range events {
+		want := last[event.Pair.To] + 1
+		if event.Pair.From != "EUR" || event.Current.IntPart() != want {
+			t.Fatalf("got %s at %s, want EUR/%s at %d", event.Pair, event.Current, event.Pair.To, want)
+		}
+		last[event.Pair.To] = want
+	}
+	for _, quote := range quotes {
+		if last[quote] != sets {
+			t.Errorf("EUR/%s: %d events, want %d", quote, last[quote], sets)
+		}
+	}
+}
+
+// pausingProvider returns from writes after a short random pause, like a
+// writer preempted right after its change was made
+type pausingProvider struct {
+	*MemoryRateProvider
+}
+
+func (p pausingProvider) RecordRatesAt(at time.Time, fn func(RateTable) ([]RateChange, error), commit CommitFunc) ([]RateDiff, error) {
+	diffs, err := p.MemoryRateProvider.RecordRatesAt(at, fn, commit)
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+	return diffs, err
+}
+
+// TestLastEventMatchesStoredRate has several writers set the same pair; the
+// events arrive in the order of the writes, so the last one carries the rate
+// that ended up stored
+func TestLastEventMatchesStoredRate(t *testing.T) {
+	provider := NewMemoryRateProvider(DefaultRateTable())
+	conv := NewCurrencyConverter(WithRateProvider(pausingProvider{provider}))
+
+	const writers, sets = 8, 50
+	events, cancel := conv.SubscribeRates(writers * sets * 2)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 1; w <= writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 1; i <= sets; i++ {
+				rate := decimal.NewFromInt(int64(w*1000 + i))
+				if err := conv.SetExchangeRate("EUR", "USD", rate); err != nil {
+					t.Errorf("SetExchangeRate: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	cancel()
+
+	var last, previous RateEvent
+	count := 0
+	for event := range events {
+		if event.Pair != (Pair{From: "EUR", To: "USD"}) {
+			continue
+		}
+		if count > 0 && !event.Previous.Equal(previous.Current) {
+			t.Fatalf("event %d follows %s but changed %s to %s", count, previous.Current, event.Previous, event.Current)
+		}
+		previous, last = event, event
+		count++
+	}
+
+	stored, err := provider.GetRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("GetRate: %v", err)
+	}
+	if count == 0 || !last.Current.Equal(stored) {
+		t.Errorf("last of %d events set %s, stored rate is %s", count, last.Current, stored)
+	}
+}
@@ -46,7 +46,7 @@ var DefaultExchangeRates = map[string]map[string]float64{
 	},
 }
 
-// SupportedCurrencies returns the list of supported currency codes
+// SupportedCurrencies returns the currency codes that have default rates
 func SupportedCurrencies() []string {
 	return []string{"USD", "EUR", "GBP", "JPY", "CAD", "AUD"}
 }
\ No newline at end of file
@@ -0,0 +1,152 @@
+package converter
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Pair identifies an exchange rate from one currency to another
+type Pair struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// String returns the pair as "FROM/TO"
+func (p Pair) String() string {
+	return p.From + "/" + p.To
+}
+
+// RatePoint is a rate that took effect at a point in time. A removed point
+// marks the moment a pair was deleted from the table.
+type RatePoint struct {
+	Rate        decimal.Decimal `json:"rate"`
+	EffectiveAt time.Time       `json:"effectiveAt"`
+	Source      string          `json:"source,omitempty"`
+	Removed     bool            `json:"removed,omitempty"`
+}
+
+// same reports whether two points set the same rate from the same source, or
+// both remove their pair, regardless of when they took effect
+func (p RatePoint) same(other RatePoint) bool {
+	if p.Removed || other.Removed {
+		return p.Removed == other.Removed
+	}
+	return p.Rate.Equal(other.Rate) && p.Source == other.Source
+}
+
+// RateChange is a single point recorded for a pair
+type RateChange struct {
+	Pair  Pair
+	Point RatePoint
+}
+
+// RateHistory holds the time series of every pair, each sorted by EffectiveAt
+type RateHistory map[Pair][]RatePoint
+
+// pointAt returns the point in effect for a pair at the given time
+func (h RateHistory) pointAt(pair Pair, at time.Time) (RatePoint, bool) {
+	points := h[pair]
+	// index of the first point that takes effect after at
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].EffectiveAt.After(at)
+	})
+	if i == 0 {
+		return RatePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// TableAt builds the rate table that was in effect at the given time
+func (h RateHistory) TableAt(at time.Time) RateTable {
+	table := make(RateTable)
+	for pair := range h {
+		if point, ok := h.pointAt(pair, at); ok && !point.Removed {
+			table.Set(pair.From, pair.To, RateEntry{
+				Rate:      point.Rate,
+				Source:    point.Source,
+				UpdatedAt: point.EffectiveAt,
+			})
+		}
+	}
+	return table
+}
+
+// Range returns the points of a pair that took effect between start and end,
+// preceded by the point already in effect at start. A zero end means no upper bound.
+func (h RateHistory) Range(pair Pair, start, end time.Time) []RatePoint {
+	var points []RatePoint
+	if point, ok := h.pointAt(pair, start); ok {
+		points = append(points, point)
+	}
+	for _, point := range h[pair] {
+		if !point.EffectiveAt.After(start) {
+			continue
+		}
+		if !end.IsZero() && point.EffectiveAt.After(end) {
+			break
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// record inserts a point into a pair's series, keeping it sorted. The slice is
+// copied so snapshots holding the previous series are not affected.
+func (h RateHistory) record(change RateChange) {
+	old := h[change.Pair]
+	i := sort.Search(len(old), func(i int) bool {
+		return old[i].EffectiveAt.After(change.Point.EffectiveAt)
+	})
+
+	points := make([]RatePoint, 0, len(old)+1)
+	points = append(points, old[:i]...)
+	points = append(points, change.Point)
+	points = append(points, old[i:]...)
+	h[change.Pair] = points
+}
+
+// NewRateHistory builds a history from recorded changes, in any order
+func NewRateHistory(changes []RateChange) RateHistory {
+	history := make(RateHistory)
+	for _, change := range changes {
+		history.record(change)
+	}
+	return history
+}
+
+// Changes flattens the history into a list of changes, ordered by pair and time
+func (h RateHistory) Changes() []RateChange {
+	pairs := make([]Pair, 0, len(h))
+	for pair := range h {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+
+	var changes []RateChange
+	for _, pair := range pairs {
+		for _, point := range h[pair] {
+			changes = append(changes, RateChange{Pair: pair, Point: point})
+		}
+	}
+	return changes
+}
+
+// SeedChanges returns the changes that record a table's entries as taking
+// effect at their UpdatedAt. Entries with a zero UpdatedAt, such as the
+// defaults, are in effect since the beginning of time, so as-of conversions
+// before any update use them too.
+func SeedChanges(table RateTable) []RateChange {
+	var changes []RateChange
+	for _, pair := range table.Pairs() {
+		entry, _ := table.Entry(pair.From, pair.To)
+		changes = append(changes, RateChange{
+			Pair:  pair,
+			Point: RatePoint{Rate: entry.Rate, EffectiveAt: entry.UpdatedAt, Source: entry.Source},
+		})
+	}
+	return changes
+}
@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRateHistoryRangeAndTableAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, time.January, d, 0, 0, 0, 0, time.UTC) }
+	pair := Pair{From: "EUR", To: "USD"}
+	point := func(rate string, d int) RateChange {
+		return RateChange{Pair: pair, Point: RatePoint{Rate: decimal.RequireFromString(rate), EffectiveAt: day(d), Source: SourceManual}}
+	}
+	// recorded out of order; the history keeps every series sorted
+	history := NewRateHistory([]RateChange{
+		point("1.10", 1),
+		point("1.20", 10),
+		{Pair: pair, Point: RatePoint{EffectiveAt: day(20), Removed: true}},
+		point("1.15", 5),
+	})
+
+	rangeTests := []struct {
+		name       string
+		start, end time.Time
+		want       []string
+	}{
+		{"everything", time.Time{}, time.Time{}, []string{"1.1@1", "1.15@5", "1.2@10", "removed@20"}},
+		{"starts with the rate in effect", day(3), day(12), []string{"1.1@1", "1.15@5", "1.2@10"}},
+		{"start on a change", day(5), day(5), []string{"1.15@5"}},
+		{"open end", day(15), time.Time{}, []string{"1.2@10", "removed@20"}},
+		{"before the first rate", day(1).AddDate(0, -1, 0), day(2), []string{"1.1@1"}},
+	}
+	for _, tt := range rangeTests {
+		var got []string
+		for _, p := range history.Range(pair, tt.start, tt.end) {
+			label := p.Rate.String()
+			if p.Removed {
+				label = "removed"
+			}
+			got = append(got, label+"@"+p.EffectiveAt.Format("2"))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Range = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: Range = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	tableTests := []struct {
+		at   time.Time
+		want string // empty when the pair is absent
+	}{
+		{day(1).Add(-time.Nanosecond), ""},
+		{day(1), "1.1"},
+		{day(7), "1.15"},
+		{day(10), "1.2"},
+		{day(19), "1.2"},
+		{day(20), ""},
+	}
+	for _, tt := range tableTests {
+		entry, ok := history.TableAt(tt.at).Entry("EUR", "USD")
+		if got := entry.Rate.String(); ok != (tt.want != "") || (ok && got != tt.want) {
+			t.Errorf("TableAt(%s) = %s, %v; want %q", tt.at.Format(time.RFC3339Nano), got, ok, tt.want)
+		}
+	}
+}
+
+func TestConvertAtBeforeAndAfterUpdates(t *testing.T) {
+	conv := NewCurrencyConverter()
+	now := time.Now()
+	if _, err := conv.SetExchangeRateAt("EUR", "USD", decimal.RequireFromString("1.25"), now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("SetExchangeRateAt: %v", err)
+	}
+	if _, err := conv.SetExchangeRateAt("EUR", "USD", decimal.RequireFromString("1.30"), now.Add(-time.Hour)); err != nil {
+		t.Fatalf("SetExchangeRateAt: %v", err)
+	}
+
+	hundred := decimal.NewFromInt(100)
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{now.Add(-3 * time.Hour), "118.00 USD"},
+		{now.Add(-90 * time.Minute), "125.00 USD"},
+		{now.Add(-time.Hour), "130.00 USD"},
+		{now, "130.00 USD"},
+	}
+	for _, tt := range tests {
+		money, err := conv.ConvertAt(hundred, "EUR", "USD", tt.at)
+		if err != nil || money.String() != tt.want {
+			t.Errorf("ConvertAt(%s ago) = %s, %v; want %s", now.Sub(tt.at), money, err, tt.want)
+		}
+		result, err := conv.ConvertWithResult(hundred, "EUR", "USD", AsOf(tt.at))
+		if err != nil || result.ConvertedAmount.String() != tt.want {
+			t.Errorf("ConvertWithResult(AsOf %s ago) = %+v, %v; want %s", now.Sub(tt.at), result, err, tt.want)
+		}
+	}
+
+	result, err := conv.ConvertWithResult(hundred, "EUR", "USD", AsOf(now.Add(-90*time.Minute)))
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if !result.RateUpdatedAt.Equal(now.Add(-2*time.Hour)) || result.RateSource != SourceManual {
+		t.Errorf("as-of rate from %s updated at %s, want the manual rate of two hours ago", result.RateSource, result.RateUpdatedAt)
+	}
+	if money, _ := conv.Convert(hundred, "EUR", "USD"); money.String() != "130.00 USD" {
+		t.Errorf("Convert = %s, want the latest 130.00 USD", money)
+	}
+}
+
+func TestConvertAtRemovedPair(t *testing.T) {
+	conv := NewCurrencyConverter()
+	before := time.Now()
+	if _, err := conv.RemoveCurrency("JPY"); err != nil {
+		t.Fatalf("RemoveCurrency: %v", err)
+	}
+
+	money, err := conv.ConvertAt(decimal.NewFromInt(1), "USD", "JPY", before)
+	if err != nil || money.String() != "110 JPY" {
+		t.Errorf("USD to JPY before the removal = %s, %v; want 110 JPY", money, err)
+	}
+	_, err = conv.ConvertAt(decimal.NewFromInt(1), "USD", "JPY", time.Now())
+	if convErr, ok := err.(ConversionError); !ok || convErr.Code != "RATE_NOT_FOUND" {
+		t.Errorf("USD to JPY after the removal: err = %v, want RATE_NOT_FOUND", err)
+	}
+
+	points := conv.GetRateHistory("USD", "JPY", time.Time{}, time.Time{})
+	if len(points) != 2 || !points[1].Removed || points[1].EffectiveAt.Before(before) {
+		t.Errorf("USD/JPY history = %+v, want the default and its removal", points)
+	}
+}
@@ -0,0 +1,169 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultArbitrageTolerance is the deviation in percent a rate cycle may have
+// before the integrity check reports it
+var DefaultArbitrageTolerance = decimal.NewFromFloat(0.5)
+
+// ArbitrageCycle is a round trip through the rate graph that does not return
+// the starting amount. Currencies starts and ends with the same currency.
+type ArbitrageCycle struct {
+	Currencies []string `json:"currencies"`
+	// Product is the amount one unit becomes after the round trip
+	Product decimal.Decimal `json:"product"`
+	// DeviationPercent is |Product-1| in percent
+	DeviationPercent decimal.Decimal `json:"deviationPercent"`
+}
+
+// IntegrityReport is the result of checking a rate table
+type IntegrityReport struct {
+	CheckedAt        time.Time       `json:"checkedAt"`
+	TolerancePercent decimal.Decimal `json:"tolerancePercent"`
+	Currencies       []string        `json:"currencies"`
+	// Cycles lists two- and three-currency round trips deviating more than
+	// the tolerance, largest deviation first
+	Cycles []ArbitrageCycle `json:"cycles"`
+	// MissingPairs lists pairs with no stored rate in either direction. They
+	// can still be converted by triangulation.
+	MissingPairs []Pair `json:"missingPairs"`
+	// Unreachable lists pairs that cannot be converted at all
+	Unreachable []Pair `json:"unreachable"`
+}
+
+// Healthy reports whether the table has no arbitrage cycles and every pair
+// can be converted
+func (r IntegrityReport) Healthy() bool {
+	return len(r.Cycles) == 0 && len(r.Unreachable) == 0
+}
+
+// Summary describes the problems found in one line
+func (r IntegrityReport) Summary() string {
+	if r.Healthy() {
+		return fmt.Sprintf("%d currencies, no arbitrage cycles above %s%%, every pair convertible",
+			len(r.Currencies), r.TolerancePercent)
+	}
+
+	var problems []string
+	if len(r.Cycles) > 0 {
+		worst := r.Cycles[0]
+		problems = append(problems, fmt.Sprintf("%d arbitrage cycles above %s%% (worst %s at %s%%)",
+			len(r.Cycles), r.TolerancePercent, strings.Join(worst.Currencies, "->"), worst.DeviationPercent.StringFixed(4)))
+	}
+	if len(r.Unreachable) > 0 {
+		problems = append(problems, fmt.Sprintf("%d unreachable pairs (e.g. %s)", len(r.Unreachable), r.Unreachable[0]))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// CheckIntegrity checks the current rate table for arbitrage cycles and
+// missing pairs. Cycles within tolerancePercent are ignored.
+func (c *CurrencyConverter) CheckIntegrity(tolerancePercent decimal.Decimal) IntegrityReport {
+	return CheckRateTable(c.rates.Snapshot(), tolerancePercent)
+}
+
+// CheckRateTable checks a rate table for two- and three-currency round trips
+// that deviate from 1 by more than tolerancePercent, and for pairs that have
+// no stored rate or cannot be converted at all. Legs without a stored rate
+// in the walked direction use the inverse of the opposite pair, as
+// conversions do.
+func CheckRateTable(table RateTable, tolerancePercent decimal.Decimal) IntegrityReport {
+	currencies := table.Currencies()
+	report := IntegrityReport{
+		CheckedAt:        time.Now(),
+		TolerancePercent: tolerancePercent,
+		Currencies:       currencies,
+		Cycles:           []ArbitrageCycle{},
+		MissingPairs:     []Pair{},
+		Unreachable:      []Pair{},
+	}
+
+	check := func(cycle ...string) {
+		product := decimal.NewFromInt(1)
+		for i := range cycle {
+			rate, ok := table.edgeRate(cycle[i], cycle[(i+1)%len(cycle)])
+			if !ok {
+				return
+			}
+			product = product.Mul(rate)
+		}
+		if percent := deviationPercent(product); percent.GreaterThan(tolerancePercent) {
+			report.Cycles = append(report.Cycles, ArbitrageCycle{
+				Currencies:       append(cycle, cycle[0]),
+				Product:          product.Round(RatePrecision),
+				DeviationPercent: percent,
+			})
+		}
+	}
+
+	component := connectedComponents(table.adjacency())
+	for i, a := range currencies {
+		for j := i + 1; j < len(currencies); j++ {
+			b := currencies[j]
+			_, ab := table.Rate(a, b)
+			_, ba := table.Rate(b, a)
+			if !ab && !ba {
+				report.MissingPairs = append(report.MissingPairs, Pair{From: a, To: b})
+				if component[a] != component[b] {
+					report.Unreachable = append(report.Unreachable, Pair{From: a, To: b})
+				}
+				continue
+			}
+			// a round trip over a single pair only deviates if both directions are stored
+			if ab && ba {
+				check(a, b)
+			}
+			for k := j + 1; k < len(currencies); k++ {
+				check(a, b, currencies[k])
+				check(a, currencies[k], b)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Cycles, func(i, j int) bool {
+		return report.Cycles[i].DeviationPercent.GreaterThan(report.Cycles[j].DeviationPercent)
+	})
+	return report
+}
+
+// deviationPercent returns |product-1| in percent
+func deviationPercent(product decimal.Decimal) decimal.Decimal {
+	return product.Sub(decimal.NewFromInt(1)).Abs().Mul(decimal.NewFromInt(100))
+}
+
+// connectedComponents labels every currency with the smallest currency of
+// the connected part of the rate graph it belongs to
+func connectedComponents(graph map[string]map[string]bool) map[string]string {
+	currencies := make([]string, 0, len(graph))
+	for code := range graph {
+		currencies = append(currencies, code)
+	}
+	sort.Strings(currencies)
+
+	component := make(map[string]string, len(graph))
+	for _, root := range currencies {
+		if _, seen := component[root]; seen {
+			continue
+		}
+		component[root] = root
+		queue := []string{root}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for next := range graph[current] {
+				if _, seen := component[next]; !seen {
+					component[next] = root
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return component
+}
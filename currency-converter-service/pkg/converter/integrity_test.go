@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func integrityTable(rates ...string) RateTable {
+	table := make(RateTable)
+	for _, r := range rates {
+		fields := strings.Fields(r)
+		table.Set(fields[0], fields[1], RateEntry{Rate: decimal.RequireFromString(fields[2]), Source: SourceManual})
+	}
+	return table
+}
+
+func TestCheckRateTableCycles(t *testing.T) {
+	half := decimal.RequireFromString("0.5")
+
+	consistent := CheckRateTable(integrityTable("USD EUR 0.8", "EUR USD 1.25", "USD GBP 0.75"), half)
+	if !consistent.Healthy() || len(consistent.Cycles) != 0 {
+		t.Errorf("consistent table: %s, cycles %+v", consistent.Summary(), consistent.Cycles)
+	}
+
+	// both directions stored, 1.3 * 0.8 = 1.04
+	twoWay := integrityTable("USD EUR 0.8", "EUR USD 1.3")
+	report := CheckRateTable(twoWay, half)
+	if report.Healthy() || len(report.Cycles) != 1 {
+		t.Fatalf("two-way cycle: %s, cycles %+v", report.Summary(), report.Cycles)
+	}
+	cycle := report.Cycles[0]
+	if strings.Join(cycle.Currencies, ",") != "EUR,USD,EUR" || !cycle.Product.Equal(decimal.RequireFromString("1.04")) ||
+		!cycle.DeviationPercent.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("two-way cycle = %+v", cycle)
+	}
+	if !strings.Contains(report.Summary(), "1 arbitrage cycles above 0.5%") {
+		t.Errorf("summary = %q", report.Summary())
+	}
+
+	// the deviation is within a larger tolerance, and exactly at it
+	for _, tolerance := range []string{"5", "4"} {
+		if report := CheckRateTable(twoWay, decimal.RequireFromString(tolerance)); !report.Healthy() {
+			t.Errorf("tolerance %s%%: %s", tolerance, report.Summary())
+		}
+	}
+
+	// 0.9 * 1.5 * 0.8 = 1.08 one way round, its inverse the other
+	triangle := CheckRateTable(integrityTable("EUR GBP 0.9", "GBP USD 1.5", "USD EUR 0.8"), half)
+	if len(triangle.Cycles) != 2 {
+		t.Fatalf("triangle cycles = %+v, want both directions", triangle.Cycles)
+	}
+	worst := triangle.Cycles[0]
+	if strings.Join(worst.Currencies, ",") != "EUR,GBP,USD,EUR" || !worst.DeviationPercent.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("worst cycle = %+v, want EUR,GBP,USD,EUR at 8%%", worst)
+	}
+	if !triangle.Cycles[1].DeviationPercent.LessThan(worst.DeviationPercent) {
+		t.Errorf("cycles not sorted by deviation: %+v", triangle.Cycles)
+	}
+}
+
+func TestCheckRateTableMissingAndUnreachablePairs(t *testing.T) {
+	tolerance := DefaultArbitrageTolerance
+
+	// EUR and GBP have no rate with each other, but convert through USD
+	report := CheckRateTable(integrityTable("USD EUR 0.8", "USD GBP 0.75"), tolerance)
+	if !report.Healthy() || len(report.MissingPairs) != 1 || report.MissingPairs[0] != (Pair{From: "EUR", To: "GBP"}) ||
+		len(report.Unreachable) != 0 {
+		t.Errorf("triangulated pair: %s, missing %v, unreachable %v", report.Summary(), report.MissingPairs, report.Unreachable)
+	}
+
+	// CHF and XAU only know each other
+	report = CheckRateTable(integrityTable("USD EUR 0.8", "CHF XAU 0.0004"), tolerance)
+	want := []Pair{{From: "CHF", To: "EUR"}, {From: "CHF", To: "USD"}, {From: "EUR", To: "XAU"}, {From: "USD", To: "XAU"}}
+	if report.Healthy() || len(report.Unreachable) != len(want) {
+		t.Fatalf("disconnected table: %s, unreachable %v", report.Summary(), report.Unreachable)
+	}
+	for i, pair := range want {
+		if report.Unreachable[i] != pair {
+			t.Errorf("unreachable = %v, want %v", report.Unreachable, want)
+			break
+		}
+	}
+	if len(report.MissingPairs) != len(want) || !strings.Contains(report.Summary(), "4 unreachable pairs (e.g. CHF/EUR)") {
+		t.Errorf("missing %v, summary %q", report.MissingPairs, report.Summary())
+	}
+}
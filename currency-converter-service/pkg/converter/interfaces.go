@@ -1,40 +1,122 @@
 package converter
 
-import "time"
+import (
+	"time"
 
-// ICurrencyConverter defines the core currency conversion interface
+	"github.com/shopspring/decimal"
+)
+
+// ICurrencyConverter defines the core currency conversion interface.
+// The API layer only depends on this interface, so decorators (caching,
+// metrics, auditing) and alternative implementations can be plugged in.
 type ICurrencyConverter interface {
-	Convert(amount float64, from, to string) (float64, error)
-	SetExchangeRate(from, to string, rate float64) error
+	Convert(amount decimal.Decimal, from, to string) (Money, error)
+	ConvertAt(amount decimal.Decimal, from, to string, at time.Time) (Money, error)
+	ConvertWithResult(amount decimal.Decimal, from, to string, opts ...ConvertOption) (*ConversionResult, error)
+	ConvertBatch(items []BatchItem, opts ...ConvertOption) ([]BatchResult, error)
+	ConvertTo(target decimal.Decimal, from, to string, opts ...ConvertOption) (*ConversionResult, error)
+	SetExchangeRate(from, to string, rate decimal.Decimal) error
+	SetExchangeRateAt(from, to string, rate decimal.Decimal, effective time.Time) ([]RateDiff, error)
+	GetRateHistory(from, to string, start, end time.Time) []RatePoint
+	ListRates(base string) []RateQuote
+	GetRateQuote(from, to string) (*RateQuote, error)
 	GetSupportedCurrencies() []string
-	ResetRates()
+	ListCurrencies() []CurrencyInfo
+	AddCurrency(currency Currency) error
+	RemoveCurrency(code string) ([]RateDiff, error)
+	ResetRates(scope ResetScope) ([]RateDiff, error)
+	ImportRates(rates RateTable) ([]RateDiff, error)
+	RemoveRatesFrom(source string) ([]RateDiff, error)
+	AuditInverseRates(thresholdPercent decimal.Decimal) []InverseDeviation
+	CheckIntegrity(tolerancePercent decimal.Decimal) IntegrityReport
+	SubscribeRates(buffer int) (<-chan RateEvent, func())
+	WithCommit(commit CommitFunc) ICurrencyConverter
 }
 
-// IExchangeRateProvider defines the exchange rate management interface
+// IExchangeRateProvider defines the exchange rate management interface.
+// Implementations store the rate table; the converter reads consistent
+// snapshots from them and never triangulates inside the provider.
 type IExchangeRateProvider interface {
-	GetRate(from, to string) (float64, error)
-	SetRate(from, to string, rate float64) error
+	GetRate(from, to string) (decimal.Decimal, error)
+	GetEntry(from, to string) (RateEntry, error)
+	SetRate(from, to string, rate decimal.Decimal) error
+	RecordRate(pair Pair, point RatePoint) error
+	RecordRates(changes []RateChange) ([]RateDiff, error)
+	RecordRatesAt(at time.Time, fn func(RateTable) ([]RateChange, error), commit CommitFunc) ([]RateDiff, error)
+	Snapshot() RateTable
+	SnapshotAt(at time.Time) RateTable
+	History(from, to string, start, end time.Time) []RatePoint
+	ReplaceRates(table RateTable) error
+	UpdateRates(fn func(RateTable) error, commit CommitFunc) ([]RateDiff, error)
+	OnChange(fn func(diffs []RateDiff))
+}
+
+// CurrencyStore is implemented by rate providers that also keep the currency
+// registry, so currencies added at runtime survive a restart together with
+// their minor units. The converter restores its default registry from it and
+// saves every change before it is made.
+type CurrencyStore interface {
+	// SavedCurrencies returns the registry as last saved, or nil if it never was
+	SavedCurrencies() []Currency
+	SaveCurrencies(currencies []Currency) error
 }
 
-// ConversionResult represents the result of a currency conversion
+// compile-time checks that the implementations satisfy the interfaces
+var (
+	_ ICurrencyConverter    = (*CurrencyConverter)(nil)
+	_ IExchangeRateProvider = (*MemoryRateProvider)(nil)
+)
+
+// RateQuote is the rate between two currencies as reported by the read API.
+// Derived rates have the source "derived" and the update time of their oldest leg.
+type RateQuote struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Source    string          `json:"source"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	RouteKind RouteKind       `json:"routeKind"`
+	Route     []string        `json:"route"`
+}
+
+// ConversionResult represents the result of a currency conversion.
+// RateSource, RateUpdatedAt and RateAge describe the rate used; for a route
+// over several pairs they refer to its oldest leg. A zero RateUpdatedAt means
+// the rate has not changed since the defaults and its age is unknown.
+//
+// ExchangeRate is the rate applied to the amount: MidRate adjusted by the
+// pricing profile's spread for Side. ConvertedAmount is the amount at that
+// rate; NetAmount is what remains after the Fee, both in the target currency.
+// Without a pricing profile the rates are equal and the fee is zero.
 type ConversionResult struct {
-	ConvertedAmount float64   `json:"convertedAmount"`
-	OriginalAmount  float64   `json:"originalAmount"`
-	FromCurrency    string    `json:"fromCurrency"`
-	ToCurrency      string    `json:"toCurrency"`
-	ExchangeRate    float64   `json:"exchangeRate"`
-	Timestamp       time.Time `json:"timestamp"`
+	ConvertedAmount Money           `json:"convertedAmount"`
+	OriginalAmount  Money           `json:"originalAmount"`
+	FromCurrency    string          `json:"fromCurrency"`
+	ToCurrency      string          `json:"toCurrency"`
+	ExchangeRate    decimal.Decimal `json:"exchangeRate"`
+	MidRate         decimal.Decimal `json:"midRate"`
+	Profile         string          `json:"profile,omitempty"`
+	Side            Side            `json:"side"`
+	Fee             Money           `json:"fee"`
+	NetAmount       Money           `json:"netAmount"`
+	RouteKind       RouteKind       `json:"routeKind"`
+	Route           []string        `json:"route"`
+	RateSource      string          `json:"rateSource,omitempty"`
+	RateUpdatedAt   time.Time       `json:"rateUpdatedAt"`
+	RateAge         time.Duration   `json:"rateAge"`
+	AsOf            *time.Time      `json:"asOf,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
 }
 
 // ConversionError represents conversion-specific errors
 type ConversionError struct {
-	Code    string  `json:"code"`
-	Message string  `json:"message"`
-	Amount  float64 `json:"amount,omitempty"`
-	From    string  `json:"from,omitempty"`
-	To      string  `json:"to,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Amount  string `json:"amount,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
 }
 
 func (e ConversionError) Error() string {
 	return e.Message
-}
\ No newline at end of file
+}
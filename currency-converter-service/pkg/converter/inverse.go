@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// InversePolicy decides how setting a rate affects the stored rate of the
+// opposite direction
+type InversePolicy string
+
+// Supported inverse policies
+const (
+	// InverseIndependent stores each direction as given
+	InverseIndependent InversePolicy = "independent"
+	// InverseDerive stores 1/rate for the opposite direction with every rate set
+	InverseDerive InversePolicy = "derive"
+	// InverseReject refuses rates whose product with the stored opposite rate
+	// deviates from 1 by more than the tolerance
+	InverseReject InversePolicy = "reject"
+)
+
+// DefaultInverseTolerance is the deviation in percent InverseReject allows
+// when no tolerance is configured
+var DefaultInverseTolerance = decimal.NewFromInt(1)
+
+// ParseInversePolicy parses a policy name
+func ParseInversePolicy(name string) (InversePolicy, error) {
+	switch InversePolicy(strings.ToLower(name)) {
+	case InverseIndependent:
+		return InverseIndependent, nil
+	case InverseDerive:
+		return InverseDerive, nil
+	case InverseReject:
+		return InverseReject, nil
+	}
+	return "", fmt.Errorf("unknown inverse policy %q (want %s, %s or %s)", name, InverseIndependent, InverseDerive, InverseReject)
+}
+
+// WithInversePolicy sets how rates of opposite directions are kept consistent.
+// tolerancePercent is only used by InverseReject; zero or less selects
+// DefaultInverseTolerance. The default policy is InverseIndependent.
+func WithInversePolicy(policy InversePolicy, tolerancePercent decimal.Decimal) Option {
+	return func(c *CurrencyConverter) {
+		c.inversePolicy = policy
+		c.inverseTolerance = tolerancePercent
+		if tolerancePercent.Sign() <= 0 {
+			c.inverseTolerance = DefaultInverseTolerance
+		}
+	}
+}
+
+// InverseDeviation reports how far a pair and its opposite direction are from
+// being exact inverses of each other
+type InverseDeviation struct {
+	Pair        Pair            `json:"pair"`
+	Rate        decimal.Decimal `json:"rate"`
+	InverseRate decimal.Decimal `json:"inverseRate"`
+	// Product is Rate*InverseRate, exactly 1 for consistent rates
+	Product decimal.Decimal `json:"product"`
+	// DeviationPercent is |Product-1| in percent
+	DeviationPercent decimal.Decimal `json:"deviationPercent"`
+}
+
+// inverseDeviation returns how far rate*inverse is from 1, in percent
+func inverseDeviation(rate, inverse decimal.Decimal) (product, percent decimal.Decimal) {
+	product = rate.Mul(inverse)
+	return product, deviationPercent(product)
+}
+
+// AuditInverseRates lists every pair stored in both directions whose rates
+// deviate from being inverses by more than thresholdPercent. Each pair is
+// reported once, from the alphabetically smaller currency, sorted by
+// deviation with the largest first.
+func (c *CurrencyConverter) AuditInverseRates(thresholdPercent decimal.Decimal) []InverseDeviation {
+	table := c.rates.Snapshot()
+
+	deviations := []InverseDeviation{}
+	for _, pair := range table.Pairs() {
+		if pair.From >= pair.To {
+			continue
+		}
+		inverse, ok := table.Rate(pair.To, pair.From)
+		if !ok {
+			continue
+		}
+		rate, _ := table.Rate(pair.From, pair.To)
+		product, percent := inverseDeviation(rate, inverse)
+		if percent.GreaterThan(thresholdPercent) {
+			deviations = append(deviations, InverseDeviation{
+				Pair:             pair,
+				Rate:             rate,
+				InverseRate:      inverse,
+				Product:          product,
+				DeviationPercent: percent,
+			})
+		}
+	}
+
+	sort.SliceStable(deviations, func(i, j int) bool {
+		return deviations[i].DeviationPercent.GreaterThan(deviations[j].DeviationPercent)
+	})
+	return deviations
+}
+
+// applyInversePolicy checks a rate about to be stored against the opposite
+// direction in table and returns the entry to store for that direction, if
+// the policy derives one
+func (c *CurrencyConverter) applyInversePolicy(table RateTable, pair Pair, entry RateEntry) (*RateEntry, error) {
+	switch c.inversePolicy {
+	case InverseDerive:
+		return &RateEntry{
+			Rate:      inverseRate(entry.Rate),
+			Source:    SourceDerived,
+			UpdatedAt: entry.UpdatedAt,
+		}, nil
+	case InverseReject:
+		inverse, ok := table.Rate(pair.To, pair.From)
+		if !ok {
+			return nil, nil
+		}
+		if _, percent := inverseDeviation(entry.Rate, inverse); percent.GreaterThan(c.inverseTolerance) {
+			return nil, ConversionError{
+				Code: "INCONSISTENT_RATE",
+				Message: fmt.Sprintf("rate %s for %s deviates %s%% from the inverse of %s/%s %s, more than the allowed %s%%",
+					entry.Rate, pair, percent.StringFixed(4), pair.To, pair.From, inverse, c.inverseTolerance),
+				From: pair.From,
+				To:   pair.To,
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package converter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestInverseDerive(t *testing.T) {
+	conv := NewCurrencyConverter(WithInversePolicy(InverseDerive, decimal.Zero))
+	if err := conv.SetExchangeRate("USD", "EUR", decimal.RequireFromString("0.8")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+	quote, err := conv.GetRateQuote("EUR", "USD")
+	if err != nil {
+		t.Fatalf("GetRateQuote: %v", err)
+	}
+	if !quote.Rate.Equal(decimal.RequireFromString("1.25")) || quote.Source != SourceDerived {
+		t.Errorf("EUR/USD = %s from %s, want 1.25 from %s", quote.Rate, quote.Source, SourceDerived)
+	}
+}
+
+func TestInverseReject(t *testing.T) {
+	conv := NewCurrencyConverter(WithInversePolicy(InverseReject, decimal.NewFromInt(2)))
+	// default EUR/USD is 1.18, so 0.85 is within 2% of its inverse
+	if err := conv.SetExchangeRate("USD", "EUR", decimal.RequireFromString("0.85")); err != nil {
+		t.Errorf("SetExchangeRate(0.85): %v", err)
+	}
+	err := conv.SetExchangeRate("USD", "EUR", decimal.RequireFromString("0.9"))
+	if convErr, ok := err.(ConversionError); !ok || convErr.Code != "INCONSISTENT_RATE" {
+		t.Errorf("SetExchangeRate(0.9) = %v, want INCONSISTENT_RATE", err)
+	}
+}
+
+// TestInverseRejectConcurrentDirections sets both directions of an unquoted
+// pair to inconsistent rates at the same time; only one of them may be stored
+func TestInverseRejectConcurrentDirections(t *testing.T) {
+	slow := func(RateHistory, []RateChange) error {
+		time.Sleep(50 * time.Microsecond)
+		return nil
+	}
+	for i := 0; i < 50; i++ {
+		provider := RestoreMemoryRateProvider(nil, slow)
+		conv := NewCurrencyConverter(WithRateProvider(provider), WithInversePolicy(InverseReject, decimal.Zero))
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, pair := range []Pair{{From: "USD", To: "EUR"}, {From: "EUR", To: "USD"}} {
+			wg.Add(1)
+			go func(j int, pair Pair) {
+				defer wg.Done()
+				errs[j] = conv.SetExchangeRate(pair.From, pair.To, decimal.NewFromInt(2))
+			}(j, pair)
+		}
+		wg.Wait()
+
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("setting both directions to 2 returned %v and %v, want exactly one INCONSISTENT_RATE", errs[0], errs[1])
+		}
+		if deviations := conv.AuditInverseRates(decimal.Zero); len(deviations) != 0 {
+			t.Fatalf("inconsistent rates stored: %+v", deviations)
+		}
+	}
+}
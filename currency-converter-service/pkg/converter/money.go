@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode selects how amounts are rounded to a currency's minor units
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest value, ties go to the even digit (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest value, ties go away from zero
+	RoundHalfUp
+	// RoundDown truncates towards zero
+	RoundDown
+)
+
+// String returns the name of the rounding mode
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundHalfEven:
+		return "half-even"
+	case RoundHalfUp:
+		return "half-up"
+	case RoundDown:
+		return "down"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// ParseRoundingMode converts a rounding mode name into a RoundingMode
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	switch strings.ToLower(name) {
+	case "half-even", "half_even", "bankers":
+		return RoundHalfEven, nil
+	case "half-up", "half_up":
+		return RoundHalfUp, nil
+	case "down", "truncate":
+		return RoundDown, nil
+	}
+	return 0, fmt.Errorf("unknown rounding mode %q", name)
+}
+
+// RatePrecision is the number of decimal places kept for derived exchange rates
+const RatePrecision int32 = 10
+
+// DefaultMinorUnits is the scale used for currencies missing from the ISO 4217 dataset
+const DefaultMinorUnits int32 = 2
+
+// CurrencyScale returns the number of minor-unit decimal places of a currency
+// according to the ISO 4217 dataset (JPY 0, USD 2, KWD 3)
+func CurrencyScale(currency string) int32 {
+	if iso, ok := LookupISO4217(currency); ok {
+		return iso.MinorUnits
+	}
+	return DefaultMinorUnits
+}
+
+// Money is a decimal amount in a given currency. Scale is the number of
+// decimal places the amount carries; it is never below the currency's minor units.
+type Money struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+	Scale    int32           `json:"scale"`
+}
+
+// NewMoney creates an amount in the given currency. The amount is kept exactly
+// as given; call Round to bring it to the currency's minor units.
+func NewMoney(amount decimal.Decimal, currency string) Money {
+	currency = strings.ToUpper(currency)
+	return newMoney(amount, currency, CurrencyScale(currency))
+}
+
+// newMoney creates an amount carrying at least minorUnits decimal places
+func newMoney(amount decimal.Decimal, currency string, minorUnits int32) Money {
+	scale := minorUnits
+	if places := -amount.Exponent(); places > scale {
+		scale = places
+	}
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+		Scale:    scale,
+	}
+}
+
+// Round returns the amount rounded to the currency's ISO 4217 minor units using the given mode
+func (m Money) Round(mode RoundingMode) Money {
+	return m.RoundTo(CurrencyScale(m.Currency), mode)
+}
+
+// RoundTo returns the amount rounded to the given number of decimal places
+func (m Money) RoundTo(scale int32, mode RoundingMode) Money {
+	m.Scale = scale
+	m.Amount = roundDecimal(m.Amount, scale, mode)
+	return m
+}
+
+// AmountString returns the amount formatted with exactly Scale decimal places
+func (m Money) AmountString() string {
+	return m.Amount.StringFixed(m.Scale)
+}
+
+// String returns the amount followed by the currency code, e.g. "12.50 USD"
+func (m Money) String() string {
+	return m.AmountString() + " " + m.Currency
+}
+
+// roundDecimal rounds a value to the given number of places
+func roundDecimal(d decimal.Decimal, places int32, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfUp:
+		return d.Round(places)
+	case RoundDown:
+		return d.Truncate(places)
+	default:
+		return d.RoundBank(places)
+	}
+}
@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMoneyRoundTo(t *testing.T) {
+	tests := []struct {
+		amount string
+		scale  int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"2.345", 2, RoundHalfEven, "2.34"},
+		{"2.355", 2, RoundHalfEven, "2.36"},
+		{"2.345", 2, RoundHalfUp, "2.35"},
+		{"-2.345", 2, RoundHalfUp, "-2.35"},
+		{"2.349", 2, RoundDown, "2.34"},
+		{"-2.349", 2, RoundDown, "-2.34"},
+		{"1234.5", 0, RoundHalfEven, "1234"},
+		{"1.0005", 3, RoundHalfUp, "1.001"},
+		{"7", 2, RoundHalfEven, "7.00"},
+	}
+	for _, tt := range tests {
+		m := NewMoney(decimal.RequireFromString(tt.amount), "usd").RoundTo(tt.scale, tt.mode)
+		if got := m.AmountString(); got != tt.want {
+			t.Errorf("RoundTo(%s, %d, %s) = %s, want %s", tt.amount, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCurrencyScale(t *testing.T) {
+	for currency, want := range map[string]int32{"USD": 2, "jpy": 0, "KWD": 3, "ZZZ": DefaultMinorUnits} {
+		if got := CurrencyScale(currency); got != want {
+			t.Errorf("CurrencyScale(%s) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestNewMoneyKeepsExtraPlaces(t *testing.T) {
+	m := NewMoney(decimal.RequireFromString("1.2345"), "USD")
+	if m.Scale != 4 || m.String() != "1.2345 USD" {
+		t.Errorf("NewMoney(1.2345 USD) = %s at scale %d, want 1.2345 USD at scale 4", m, m.Scale)
+	}
+	if got := m.Round(RoundHalfEven).String(); got != "1.23 USD" {
+		t.Errorf("Round = %s, want 1.23 USD", got)
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	for name, want := range map[string]RoundingMode{"half-even": RoundHalfEven, "BANKERS": RoundHalfEven, "half_up": RoundHalfUp, "truncate": RoundDown} {
+		got, err := ParseRoundingMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseRoundingMode(%q) = %s, %v; want %s", name, got, err, want)
+		}
+	}
+	if _, err := ParseRoundingMode("ceiling"); err == nil {
+		t.Error("ParseRoundingMode(ceiling) succeeded, want an error")
+	}
+}
+
+func TestConvertRoundsToTargetMinorUnits(t *testing.T) {
+	table := RateTable{}
+	table.Set("USD", "JPY", RateEntry{Rate: decimal.RequireFromString("149.875"), Source: SourceManual})
+	table.Set("USD", "KWD", RateEntry{Rate: decimal.RequireFromString("0.30745"), Source: SourceManual})
+
+	tests := []struct {
+		mode RoundingMode
+		to   string
+		want string
+	}{
+		{RoundHalfEven, "JPY", "1499 JPY"}, // 1498.75
+		{RoundDown, "JPY", "1498 JPY"},
+		{RoundHalfEven, "KWD", "3.074 KWD"}, // 3.0745, tie goes to the even digit
+		{RoundHalfUp, "KWD", "3.075 KWD"},
+	}
+	for _, tt := range tests {
+		conv := NewCurrencyConverter(WithRateProvider(NewMemoryRateProvider(table)), WithRoundingMode(tt.mode))
+		got, err := conv.Convert(decimal.NewFromInt(10), "USD", tt.to)
+		if err != nil {
+			t.Fatalf("Convert(10 USD to %s): %v", tt.to, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Convert(10 USD to %s, %s) = %s, want %s", tt.to, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Side selects which rate of a quote a conversion uses
+type Side string
+
+// Supported sides. For a conversion from A to B the customer sells A, so the
+// bid is the rate a bank pays and the ask the rate it charges.
+const (
+	// SideMid uses the stored mid-market rate without spread
+	SideMid Side = "mid"
+	// SideBid uses the bank's buying rate for the source currency; this is
+	// the default when a pricing profile applies
+	SideBid Side = "bid"
+	// SideAsk uses the bank's selling rate for the source currency
+	SideAsk Side = "ask"
+)
+
+// ParseSide parses a side name
+func ParseSide(name string) (Side, error) {
+	switch Side(strings.ToLower(name)) {
+	case SideMid:
+		return SideMid, nil
+	case SideBid, "sell":
+		return SideBid, nil
+	case SideAsk, "buy":
+		return SideAsk, nil
+	}
+	return "", fmt.Errorf("unknown side %q (want %s, %s or %s)", name, SideMid, SideBid, SideAsk)
+}
+
+// BidAsk is a quoted pair of rates for one currency pair
+type BidAsk struct {
+	Bid decimal.Decimal `json:"bid" yaml:"bid"`
+	Ask decimal.Decimal `json:"ask" yaml:"ask"`
+}
+
+// FeeSchedule describes the fee charged on a conversion. The fee is Percent of
+// the converted amount plus Fixed, limited to the range Min to Max. Fixed, Min
+// and Max are in Currency, or the target currency when Currency is empty; zero
+// Min and Max mean no cap.
+type FeeSchedule struct {
+	Percent  decimal.Decimal `json:"percent" yaml:"percent"`
+	Fixed    decimal.Decimal `json:"fixed" yaml:"fixed"`
+	Currency string          `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Min      decimal.Decimal `json:"min" yaml:"min"`
+	Max      decimal.Decimal `json:"max" yaml:"max"`
+}
+
+// PricingProfile is a bank's pricing: quoted bid/ask rates for some pairs, a
+// percentage markup around the mid rate for all others, and a fee schedule
+type PricingProfile struct {
+	Name          string
+	MarkupPercent decimal.Decimal
+	Quotes        map[Pair]BidAsk
+	Fees          FeeSchedule
+}
+
+// WithPricingProfiles registers pricing profiles that conversions can select
+// by name. When defaultProfile is not empty it applies to conversions that do
+// not name a profile; otherwise those convert at the mid rate without fees.
+func WithPricingProfiles(defaultProfile string, profiles ...PricingProfile) Option {
+	return func(c *CurrencyConverter) {
+		c.profiles = make(map[string]PricingProfile, len(profiles))
+		for _, profile := range profiles {
+			c.profiles[profile.Name] = profile
+		}
+		c.defaultProfile = defaultProfile
+	}
+}
+
+// Validate checks that a profile's rates, markup and fees make sense
+func (p PricingProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("pricing profile name is required")
+	}
+	if p.MarkupPercent.Sign() < 0 || p.MarkupPercent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return fmt.Errorf("profile %s: markup must be between 0 and 100 percent", p.Name)
+	}
+	for pair, quote := range p.Quotes {
+		if quote.Bid.Sign() <= 0 || quote.Ask.LessThan(quote.Bid) {
+			return fmt.Errorf("profile %s: %s needs 0 < bid <= ask", p.Name, pair)
+		}
+	}
+	fees := p.Fees
+	if fees.Percent.Sign() < 0 || fees.Fixed.Sign() < 0 || fees.Min.Sign() < 0 || fees.Max.Sign() < 0 {
+		return fmt.Errorf("profile %s: fees cannot be negative", p.Name)
+	}
+	if fees.Max.Sign() > 0 && fees.Max.LessThan(fees.Min) {
+		return fmt.Errorf("profile %s: maximum fee is below the minimum", p.Name)
+	}
+	return nil
+}
+
+// profile resolves the pricing profile a conversion uses; nil means mid rate
+// without fees
+func (c *CurrencyConverter) profile(name string) (*PricingProfile, error) {
+	if name == "" {
+		name = c.defaultProfile
+	}
+	if name == "" {
+		return nil, nil
+	}
+	profile, ok := c.profiles[name]
+	if !ok {
+		return nil, ConversionError{
+			Code:    "UNKNOWN_PROFILE",
+			Message: fmt.Sprintf("pricing profile %q does not exist", name),
+		}
+	}
+	return &profile, nil
+}
+
+// appliedRate returns the rate the profile charges for converting from one
+// currency to another on the given side. Quoted pairs use their bid or ask,
+// the opposite direction of a quoted pair uses its reciprocal, and every other
+// pair applies the markup to the mid rate.
+func (p *PricingProfile) appliedRate(mid decimal.Decimal, from, to string, side Side) decimal.Decimal {
+	if side == SideMid {
+		return mid
+	}
+	if quote, ok := p.Quotes[Pair{From: from, To: to}]; ok {
+		if side == SideAsk {
+			return quote.Ask
+		}
+		return quote.Bid
+	}
+	// selling B for A at the A/B quote: the bank buys B at 1/ask and sells it at 1/bid
+	if quote, ok := p.Quotes[Pair{From: to, To: from}]; ok {
+		if side == SideAsk {
+			return inverseRate(quote.Bid)
+		}
+		return inverseRate(quote.Ask)
+	}
+
+	markup := p.MarkupPercent.Div(decimal.NewFromInt(100))
+	if side == SideAsk {
+		return mid.Mul(decimal.NewFromInt(1).Add(markup)).Round(RatePrecision)
+	}
+	return mid.Mul(decimal.NewFromInt(1).Sub(markup)).Round(RatePrecision)
+}
+
+// fee computes the fee on a gross amount in the target currency. feeRate
+// converts the schedule's currency into the target currency.
+func (f FeeSchedule) fee(gross decimal.Decimal, feeRate decimal.Decimal) decimal.Decimal {
+	fee := gross.Mul(f.Percent).Div(decimal.NewFromInt(100)).Add(f.Fixed.Mul(feeRate))
+	if min := f.Min.Mul(feeRate); fee.LessThan(min) {
+		fee = min
+	}
+	if f.Max.Sign() > 0 {
+		if max := f.Max.Mul(feeRate); fee.GreaterThan(max) {
+			fee = max
+		}
+	}
+	return fee
+}
@@ -0,0 +1,92 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAppliedRate(t *testing.T) {
+	profile := &PricingProfile{
+		Name:          "bank",
+		MarkupPercent: decimal.NewFromInt(2),
+		Quotes: map[Pair]BidAsk{
+			{From: "EUR", To: "USD"}: {Bid: decimal.RequireFromString("1.25"), Ask: decimal.RequireFromString("1.6")},
+		},
+	}
+
+	tests := []struct {
+		name, from, to string
+		mid            string
+		side           Side
+		want           string
+	}{
+		{"mid ignores the quote", "EUR", "USD", "1.3", SideMid, "1.3"},
+		{"quoted bid", "EUR", "USD", "1.3", SideBid, "1.25"},
+		{"quoted ask", "EUR", "USD", "1.3", SideAsk, "1.6"},
+		// the bank buys USD at 1/ask and sells it at 1/bid
+		{"reciprocal bid", "USD", "EUR", "0.77", SideBid, "0.625"},
+		{"reciprocal ask", "USD", "EUR", "0.77", SideAsk, "0.8"},
+		{"markup on the bid", "USD", "JPY", "110", SideBid, "107.8"},
+		{"markup on the ask", "USD", "JPY", "110", SideAsk, "112.2"},
+		{"mid ignores the markup", "USD", "JPY", "110", SideMid, "110"},
+	}
+	for _, tt := range tests {
+		got := profile.appliedRate(decimal.RequireFromString(tt.mid), tt.from, tt.to, tt.side)
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: %s to %s on the %s = %s, want %s", tt.name, tt.from, tt.to, tt.side, got, tt.want)
+		}
+	}
+}
+
+func TestFeeScheduleFee(t *testing.T) {
+	d := decimal.RequireFromString
+	tests := []struct {
+		name        string
+		fees        FeeSchedule
+		gross, rate string
+		want        string
+	}{
+		{"percent", FeeSchedule{Percent: d("1")}, "200", "1", "2"},
+		{"percent and fixed", FeeSchedule{Percent: d("1"), Fixed: d("0.5")}, "200", "1", "2.5"},
+		{"raised to the minimum", FeeSchedule{Percent: d("1"), Min: d("5")}, "100", "1", "5"},
+		{"capped at the maximum", FeeSchedule{Percent: d("1"), Max: d("3")}, "1000", "1", "3"},
+		{"zero maximum is no cap", FeeSchedule{Percent: d("1")}, "1000", "1", "10"},
+		// fixed, minimum and maximum are in a currency worth 2 target units
+		{"fixed in another currency", FeeSchedule{Percent: d("1"), Fixed: d("1")}, "100", "2", "3"},
+		{"minimum in another currency", FeeSchedule{Percent: d("1"), Fixed: d("1"), Min: d("3")}, "100", "2", "6"},
+		{"maximum in another currency", FeeSchedule{Percent: d("1"), Max: d("4")}, "1000", "2", "8"},
+	}
+	for _, tt := range tests {
+		if got := tt.fees.fee(d(tt.gross), d(tt.rate)); !got.Equal(d(tt.want)) {
+			t.Errorf("%s: fee on %s = %s, want %s", tt.name, tt.gross, got, tt.want)
+		}
+	}
+}
+
+func TestConvertWithFeeInAnotherCurrency(t *testing.T) {
+	// one USD per conversion, charged in the target currency
+	conv := NewCurrencyConverter(WithPricingProfiles("retail", PricingProfile{
+		Name: "retail",
+		Fees: FeeSchedule{Fixed: decimal.NewFromInt(1), Currency: "usd"},
+	}))
+
+	result, err := conv.ConvertWithResult(decimal.NewFromInt(100), "USD", "JPY")
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if result.Profile != "retail" || result.Side != SideBid || result.ConvertedAmount.String() != "11000 JPY" ||
+		result.Fee.String() != "110 JPY" || result.NetAmount.String() != "10890 JPY" {
+		t.Errorf("100 USD to JPY = %s less %s is %s on the %s of %q",
+			result.ConvertedAmount, result.Fee, result.NetAmount, result.Side, result.Profile)
+	}
+
+	// a fee equal to the converted amount leaves nothing
+	_, err = conv.ConvertWithResult(decimal.NewFromInt(1), "USD", "JPY")
+	if convErr, ok := err.(ConversionError); !ok || convErr.Code != "FEE_EXCEEDS_AMOUNT" {
+		t.Errorf("1 USD to JPY: err = %v, want FEE_EXCEEDS_AMOUNT", err)
+	}
+	if _, err := conv.ConvertWithResult(decimal.NewFromInt(2), "USD", "JPY"); err != nil {
+		t.Errorf("2 USD to JPY: %v", err)
+	}
+}
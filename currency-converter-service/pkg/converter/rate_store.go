@@ -0,0 +1,268 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PersistFunc stores rate changes before they become visible to readers. It
+// receives the changes being made and the full history including them.
+type PersistFunc func(history RateHistory, changes []RateChange) error
+
+// CommitFunc receives the changes of the current table while a provider
+// makes them, under its write lock and before they are persisted or visible
+// to readers. An error discards the changes.
+type CommitFunc func(diffs []RateDiff) error
+
+// rateState is an immutable view of the provider: the full history and the
+// table currently in effect
+type rateState struct {
+	current RateTable
+	history RateHistory
+}
+
+// MemoryRateProvider keeps the rate history in memory behind an atomic pointer.
+// Readers take a snapshot without locking; writers serialise on a mutex, copy
+// the current state, modify the copy and publish it. A snapshot therefore
+// never changes underneath a conversion, even while an update is in progress.
+type MemoryRateProvider struct {
+	mu        sync.Mutex
+	state     atomic.Pointer[rateState]
+	persist   PersistFunc
+	observers []func(diffs []RateDiff)
+}
+
+// NewMemoryRateProvider creates an in-memory provider holding a copy of the
+// given table, in effect since the beginning of time
+func NewMemoryRateProvider(table RateTable) *MemoryRateProvider {
+	return RestoreMemoryRateProvider(SeedChanges(table), nil)
+}
+
+// RestoreMemoryRateProvider rebuilds a provider from previously recorded
+// changes. If persist is not nil it is called for every later change, and the
+// change is discarded if persist fails.
+func RestoreMemoryRateProvider(changes []RateChange, persist PersistFunc) *MemoryRateProvider {
+	history := NewRateHistory(changes)
+	p := &MemoryRateProvider{persist: persist}
+	p.state.Store(&rateState{
+		current: history.TableAt(time.Now()),
+		history: history,
+	})
+	return p
+}
+
+// OnChange registers fn to receive the changes of the current table once
+// readers can see them. It is called under the write lock, so changes arrive
+// in the order they were made; fn must not block or change rates itself.
+func (p *MemoryRateProvider) OnChange(fn func(diffs []RateDiff)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.observers = append(p.observers, fn)
+}
+
+// GetRate returns the stored rate for a pair without triangulation
+func (p *MemoryRateProvider) GetRate(from, to string) (decimal.Decimal, error) {
+	entry, err := p.GetEntry(from, to)
+	return entry.Rate, err
+}
+
+// GetEntry returns the stored entry for a pair, with its source and update time
+func (p *MemoryRateProvider) GetEntry(from, to string) (RateEntry, error) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+
+	if entry, ok := p.Snapshot().Entry(from, to); ok {
+		return entry, nil
+	}
+	return RateEntry{}, ConversionError{
+		Code:    "RATE_NOT_FOUND",
+		Message: fmt.Sprintf("exchange rate not found for %s to %s", from, to),
+		From:    from,
+		To:      to,
+	}
+}
+
+// SetRate stores a manually set rate for a pair, effective now
+func (p *MemoryRateProvider) SetRate(from, to string, rate decimal.Decimal) error {
+	return p.RecordRate(Pair{From: from, To: to}, RatePoint{
+		Rate:        rate,
+		EffectiveAt: time.Now(),
+		Source:      SourceManual,
+	})
+}
+
+// RecordRate records a rate for a pair that took effect at the point's time.
+// Backdated rates only change the current table if nothing newer exists.
+func (p *MemoryRateProvider) RecordRate(pair Pair, point RatePoint) error {
+	_, err := p.RecordRates([]RateChange{{Pair: pair, Point: point}})
+	return err
+}
+
+// RecordRates records several rates at once; either all of them become
+// visible to readers or, if one is invalid or persisting fails, none do. It
+// returns the changes of the current table, computed under the same lock as
+// the write so they never include another writer's changes.
+func (p *MemoryRateProvider) RecordRates(changes []RateChange) ([]RateDiff, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.recordLocked(changes, nil)
+}
+
+// RecordRatesAt passes the table that was in effect at the given time to fn
+// and records the changes fn returns. Reading the table and recording the
+// changes happen under one lock, so checks fn makes against the table still
+// hold when the changes are written. If commit is not nil it is called with
+// the changes of the current table before they are made.
+func (p *MemoryRateProvider) RecordRatesAt(at time.Time, fn func(RateTable) ([]RateChange, error), commit CommitFunc) ([]RateDiff, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changes, err := fn(p.state.Load().history.TableAt(at))
+	if err != nil {
+		return nil, err
+	}
+	return p.recordLocked(changes, commit)
+}
+
+// recordLocked validates and records changes and returns the resulting
+// changes of the current table. The caller must hold the lock.
+func (p *MemoryRateProvider) recordLocked(changes []RateChange, commit CommitFunc) ([]RateDiff, error) {
+	now := time.Now()
+	recorded := make([]RateChange, len(changes))
+	for i, change := range changes {
+		if change.Point.EffectiveAt.After(now) {
+			return nil, ConversionError{
+				Code:    "INVALID_DATE",
+				Message: "effective date cannot be in the future",
+			}
+		}
+		recorded[i] = RateChange{
+			Pair:  Pair{From: strings.ToUpper(change.Pair.From), To: strings.ToUpper(change.Pair.To)},
+			Point: change.Point,
+		}
+	}
+
+	return p.applyLocked(recorded, commit)
+}
+
+// Snapshot returns the table currently in effect. It must not be modified.
+func (p *MemoryRateProvider) Snapshot() RateTable {
+	return p.state.Load().current
+}
+
+// SnapshotAt returns the table that was in effect at the given time
+func (p *MemoryRateProvider) SnapshotAt(at time.Time) RateTable {
+	return p.state.Load().history.TableAt(at)
+}
+
+// History returns the recorded rates of a pair between start and end
+func (p *MemoryRateProvider) History(from, to string, start, end time.Time) []RatePoint {
+	pair := Pair{From: strings.ToUpper(from), To: strings.ToUpper(to)}
+	return p.state.Load().history.Range(pair, start, end)
+}
+
+// ReplaceRates makes the given table current. Pairs that change or disappear
+// are recorded in the history; earlier rates stay available for as-of lookups.
+func (p *MemoryRateProvider) ReplaceRates(table RateTable) error {
+	_, err := p.UpdateRates(func(next RateTable) error {
+		for from := range next {
+			delete(next, from)
+		}
+		for from, toRates := range table {
+			for to, entry := range toRates {
+				next.Set(from, to, entry)
+			}
+		}
+		return nil
+	}, nil)
+	return err
+}
+
+// UpdateRates applies fn to a copy of the current table and records every
+// pair that differs afterwards. Changed entries take effect at their
+// UpdatedAt, or now if it is zero; removals take effect now. The read, modify
+// and write happen under one lock, so concurrent updates are never lost. If
+// commit is not nil it is called with the changes of the current table
+// before they are made.
+func (p *MemoryRateProvider) UpdateRates(fn func(RateTable) error, commit CommitFunc) ([]RateDiff, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := p.state.Load().current
+	next := current.Clone()
+	if err := fn(next); err != nil {
+		return nil, err
+	}
+
+	history := p.state.Load().history
+	now := time.Now()
+	var changes []RateChange
+	for _, diff := range DiffTables(current, next) {
+		entry, ok := next.Entry(diff.Pair.From, diff.Pair.To)
+		point := RatePoint{
+			Rate:        entry.Rate,
+			EffectiveAt: entry.UpdatedAt,
+			Source:      entry.Source,
+			Removed:     !ok,
+		}
+		if !ok || point.EffectiveAt.IsZero() {
+			point.EffectiveAt = now
+		}
+		// a backdated entry behind a newer one differs from the current
+		// table every time it is applied; record it only once
+		if inEffect, ok := history.pointAt(diff.Pair, point.EffectiveAt); ok && inEffect.same(point) {
+			continue
+		}
+		changes = append(changes, RateChange{Pair: diff.Pair, Point: point})
+	}
+
+	// the changes fn intended can differ from those that took effect, as
+	// backdated entries do not replace newer ones
+	return p.applyLocked(changes, commit)
+}
+
+// applyLocked records changes on a copy of the history, passes the resulting
+// changes of the current table to commit, persists them, publishes the new
+// state and notifies the observers. It returns the changes of the current
+// table. The caller must hold the lock.
+func (p *MemoryRateProvider) applyLocked(changes []RateChange, commit CommitFunc) ([]RateDiff, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	old := p.state.Load().history
+	history := make(RateHistory, len(old)+len(changes))
+	for pair, points := range old {
+		history[pair] = points
+	}
+	for _, change := range changes {
+		history.record(change)
+	}
+
+	current := history.TableAt(time.Now())
+	diffs := DiffTables(p.state.Load().current, current)
+	if commit != nil && len(diffs) > 0 {
+		if err := commit(diffs); err != nil {
+			return nil, err
+		}
+	}
+	if p.persist != nil {
+		if err := p.persist(history, changes); err != nil {
+			return nil, err
+		}
+	}
+
+	p.state.Store(&rateState{current: current, history: history})
+	if len(diffs) > 0 {
+		for _, fn := range p.observers {
+			fn(diffs)
+		}
+	}
+	return diffs, nil
+}
@@ -0,0 +1,181 @@
+package converter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// uniformTable returns a table quoting every currency against EUR at rate
+func uniformTable(rate decimal.Decimal, quotes ...string) RateTable {
+	table := RateTable{}
+	for _, quote := range quotes {
+		table.Set("EUR", quote, RateEntry{Rate: rate, Source: SourceManual})
+	}
+	return table
+}
+
+// TestConcurrentConvertSetReset hammers conversions, rate updates and resets
+// from several goroutines; run with -race
+func TestConcurrentConvertSetReset(t *testing.T) {
+	conv := NewCurrencyConverter()
+	amount := decimal.NewFromInt(100)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, err := conv.Convert(amount, "USD", "EUR"); err != nil {
+					t.Errorf("Convert: %v", err)
+					return
+				}
+				if _, err := conv.ConvertWithResult(amount, "GBP", "JPY"); err != nil {
+					t.Errorf("ConvertWithResult: %v", err)
+					return
+				}
+			}
+		}()
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				rate := decimal.NewFromFloat(0.80).Add(decimal.New(int64(w*200+i), -5))
+				if err := conv.SetExchangeRate("USD", "EUR", rate); err != nil {
+					t.Errorf("SetExchangeRate: %v", err)
+					return
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := conv.ResetRates(ResetScope{From: "USD"}); err != nil {
+					t.Errorf("ResetRates: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := conv.ResetRates(ResetScope{}); err != nil {
+		t.Fatalf("ResetRates: %v", err)
+	}
+	rate, err := conv.GetRate("USD", "EUR")
+	if err != nil {
+		t.Fatalf("GetRate: %v", err)
+	}
+	if want := DefaultRateTable()["USD"]["EUR"].Rate; !rate.Equal(want) {
+		t.Errorf("USD/EUR after reset = %s, want default %s", rate, want)
+	}
+}
+
+// TestConvertBatchSnapshotConsistent checks that every item of a batch is
+// converted against the same table while the table is replaced and updated
+// as a whole
+func TestConvertBatchSnapshotConsistent(t *testing.T) {
+	quotes := []string{"USD", "GBP", "JPY", "CHF", "CAD", "AUD"}
+	provider := NewMemoryRateProvider(uniformTable(decimal.NewFromInt(1), quotes...))
+	conv := NewCurrencyConverter(WithRateProvider(provider))
+
+	items := make([]BatchItem, len(quotes))
+	for i, quote := range quotes {
+		items[i] = BatchItem{ID: quote, Amount: decimal.NewFromInt(1), From: "EUR", To: quote}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			rate := decimal.NewFromInt(int64(i%7 + 1))
+			if i%2 == 0 {
+				if err := provider.ReplaceRates(uniformTable(rate, quotes...)); err != nil {
+					t.Errorf("ReplaceRates: %v", err)
+					return
+				}
+				continue
+			}
+			_, err := provider.UpdateRates(func(table RateTable) error {
+				for _, quote := range quotes {
+					table.Set("EUR", quote, RateEntry{Rate: rate, Source: SourceManual})
+				}
+				return nil
+			}, nil)
+			if err != nil {
+				t.Errorf("UpdateRates: %v", err)
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				results, err := conv.ConvertBatch(items)
+				if err != nil {
+					t.Errorf("ConvertBatch: %v", err)
+					return
+				}
+				for _, result := range results {
+					if result.Error != nil {
+						t.Errorf("item %s: %v", result.ID, result.Error)
+						return
+					}
+					if first := results[0].Result.ExchangeRate; !result.Result.ExchangeRate.Equal(first) {
+						t.Errorf("batch mixed tables: %s at %s, %s at %s",
+							results[0].ID, first, result.ID, result.Result.ExchangeRate)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestSetExchangeRateDiffsOwnChanges checks that concurrent writers each get
+// back only the changes they made themselves
+func TestSetExchangeRateDiffsOwnChanges(t *testing.T) {
+	// a slow store keeps writers queued behind each other
+	slow := func(RateHistory, []RateChange) error {
+		time.Sleep(50 * time.Microsecond)
+		return nil
+	}
+	provider := RestoreMemoryRateProvider(SeedChanges(DefaultRateTable()), slow)
+	conv := NewCurrencyConverter(WithRateProvider(provider))
+	quotes := []string{"USD", "GBP", "JPY", "CHF"}
+
+	var wg sync.WaitGroup
+	for _, quote := range quotes {
+		wg.Add(1)
+		go func(quote string) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				rate := decimal.NewFromInt(int64(i + 1))
+				diffs, err := conv.SetExchangeRateAt("EUR", quote, rate, time.Now())
+				if err != nil {
+					t.Errorf("SetExchangeRateAt: %v", err)
+					return
+				}
+				if len(diffs) != 1 || diffs[0].Pair != (Pair{From: "EUR", To: quote}) || !diffs[0].Current.Equal(rate) {
+					t.Errorf("setting EUR/%s to %s returned %+v", quote, rate, diffs)
+					return
+				}
+			}
+		}(quote)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rate sources recorded by the converter itself
+const (
+	// SourceDefault marks rates taken from DefaultExchangeRates
+	SourceDefault = "default"
+	// SourceManual marks rates set through SetExchangeRate
+	SourceManual = "manual"
+	// SourceDerived marks rates triangulated from other pairs
+	SourceDerived = "derived"
+	// SourceFeed marks rates imported from an external rate feed
+	SourceFeed = "feed"
+)
+
+// RateEntry is a stored rate together with where it came from and when it
+// last changed. A zero UpdatedAt means the rate has been in effect since the
+// beginning of recorded history.
+type RateEntry struct {
+	Rate      decimal.Decimal `json:"rate"`
+	Source    string          `json:"source"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// RateTable maps a base currency to its quote currencies and their rates.
+// A RateTable returned by a snapshot must be treated as read-only.
+type RateTable map[string]map[string]RateEntry
+
+// Rate looks up a pair in the table without triangulation
+func (t RateTable) Rate(from, to string) (decimal.Decimal, bool) {
+	entry, ok := t.Entry(from, to)
+	return entry.Rate, ok
+}
+
+// Entry looks up the stored entry of a pair without triangulation
+func (t RateTable) Entry(from, to string) (RateEntry, bool) {
+	if fromRates, exists := t[from]; exists {
+		if entry, exists := fromRates[to]; exists {
+			return entry, true
+		}
+	}
+	return RateEntry{}, false
+}
+
+// Set stores an entry in the table, creating the base currency entry if needed
+func (t RateTable) Set(from, to string, entry RateEntry) {
+	if t[from] == nil {
+		t[from] = make(map[string]RateEntry)
+	}
+	t[from][to] = entry
+}
+
+// Delete removes a pair from the table, dropping the base currency entry once it is empty
+func (t RateTable) Delete(from, to string) {
+	delete(t[from], to)
+	if len(t[from]) == 0 {
+		delete(t, from)
+	}
+}
+
+// Pairs returns every pair in the table, sorted
+func (t RateTable) Pairs() []Pair {
+	var pairs []Pair
+	for from, toRates := range t {
+		for to := range toRates {
+			pairs = append(pairs, Pair{From: from, To: to})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].String() < pairs[j].String()
+	})
+	return pairs
+}
+
+// Currencies returns every currency that appears in the table, sorted
+func (t RateTable) Currencies() []string {
+	seen := make(map[string]bool)
+	for from, toRates := range t {
+		seen[from] = true
+		for to := range toRates {
+			seen[to] = true
+		}
+	}
+
+	codes := make([]string, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Clone returns a deep copy of the table
+func (t RateTable) Clone() RateTable {
+	clone := make(RateTable, len(t))
+	for from, toRates := range t {
+		clone[from] = make(map[string]RateEntry, len(toRates))
+		for to, entry := range toRates {
+			clone[from][to] = entry
+		}
+	}
+	return clone
+}
+
+// RateDiff describes how a pair changed between two tables. A zero Previous
+// means the pair was added and a zero Current means it was removed.
+type RateDiff struct {
+	Pair     Pair
+	Previous decimal.Decimal
+	Current  decimal.Decimal
+}
+
+// DiffTables lists the pairs whose entry differs between two tables, sorted
+// by pair. An entry that keeps its rate but gets a new source or update time
+// is listed too, with the same Previous and Current rate.
+func DiffTables(old, next RateTable) []RateDiff {
+	var diffs []RateDiff
+	for _, pair := range old.Pairs() {
+		before, _ := old.Entry(pair.From, pair.To)
+		after, ok := next.Entry(pair.From, pair.To)
+		if !ok || !after.Rate.Equal(before.Rate) || after.Source != before.Source || !after.UpdatedAt.Equal(before.UpdatedAt) {
+			diffs = append(diffs, RateDiff{Pair: pair, Previous: before.Rate, Current: after.Rate})
+		}
+	}
+	for _, pair := range next.Pairs() {
+		if _, ok := old.Entry(pair.From, pair.To); !ok {
+			after, _ := next.Entry(pair.From, pair.To)
+			diffs = append(diffs, RateDiff{Pair: pair, Current: after.Rate})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Pair.String() < diffs[j].Pair.String()
+	})
+	return diffs
+}
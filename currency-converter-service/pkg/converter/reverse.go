@@ -0,0 +1,160 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxReverseSteps bounds how many minor units ConvertTo moves away from its
+// estimate while searching for the smallest sufficient source amount
+const maxReverseSteps = 1000
+
+// ConvertTo solves for the smallest source amount whose forward conversion
+// yields at least target in the target currency after spread, fees and
+// rounding. It returns the forward result for that amount, so NetAmount may
+// exceed target by the rounding of the source currency's minor unit.
+func (c *CurrencyConverter) ConvertTo(target decimal.Decimal, from, to string, opts ...ConvertOption) (*ConversionResult, error) {
+	if target.Sign() <= 0 {
+		return nil, ConversionError{
+			Code:    "INVALID_AMOUNT",
+			Message: "target amount must be greater than zero",
+			Amount:  target.String(),
+		}
+	}
+
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	o := NewConvertOptions(opts...)
+	table := c.snapshot(o)
+
+	rate, err := c.appliedRate(table, o, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	gross, err := c.grossFor(table, o, target, to)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := c.currencies.Scale(from)
+	unit := decimal.New(1, -scale)
+	// RoundCeil leaves exact quotients at RatePrecision places; Round brings
+	// them to the minor units
+	source := gross.DivRound(rate, RatePrecision).RoundCeil(scale).Round(scale)
+	if source.LessThan(unit) {
+		source = unit
+	}
+
+	// the estimate ignores rounding; step until it is the smallest amount that suffices
+	result, err := c.reverseStep(table, o, source, target, from, to, unit)
+	if err != nil {
+		return nil, err
+	}
+	o.annotate(result)
+	return result, nil
+}
+
+// appliedRate returns the rate a conversion with these options applies,
+// including the pricing profile's spread
+func (c *CurrencyConverter) appliedRate(table RateTable, o ConvertOptions, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	rate, _, err := table.findRoute(from, to, c.pivotCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	profile, err := c.profile(o.Profile)
+	if err != nil || profile == nil {
+		return rate, err
+	}
+	return profile.appliedRate(rate, from, to, o.side()), nil
+}
+
+// grossFor estimates the converted amount before fees that leaves target
+// after the fees of the selected profile
+func (c *CurrencyConverter) grossFor(table RateTable, o ConvertOptions, target decimal.Decimal, to string) (decimal.Decimal, error) {
+	profile, err := c.profile(o.Profile)
+	if err != nil || profile == nil {
+		return target, err
+	}
+
+	fees := profile.Fees
+	feeRate := decimal.NewFromInt(1)
+	if feeCurrency := strings.ToUpper(fees.Currency); feeCurrency != "" && feeCurrency != to {
+		if feeRate, _, err = table.findRoute(feeCurrency, to, c.pivotCurrency); err != nil {
+			return decimal.Zero, err
+		}
+	}
+
+	// net = gross - (gross*p + fixed), solved for gross, then adjusted for the caps
+	hundred := decimal.NewFromInt(100)
+	keep := hundred.Sub(fees.Percent).Div(hundred)
+	if keep.Sign() <= 0 {
+		return decimal.Zero, ConversionError{
+			Code:    "FEE_EXCEEDS_AMOUNT",
+			Message: "the percentage fee leaves nothing of any converted amount",
+			Amount:  target.String(),
+			To:      to,
+		}
+	}
+	gross := target.Add(fees.Fixed.Mul(feeRate)).DivRound(keep, RatePrecision)
+	fee := gross.Sub(target)
+	if min := fees.Min.Mul(feeRate); fee.LessThan(min) {
+		gross = target.Add(min)
+	}
+	if fees.Max.Sign() > 0 {
+		if max := fees.Max.Mul(feeRate); fee.GreaterThan(max) {
+			gross = target.Add(max)
+		}
+	}
+	return gross, nil
+}
+
+// reverseStep moves source up until its net amount reaches target, then down
+// while it still does, and returns the forward result of the amount found
+func (c *CurrencyConverter) reverseStep(table RateTable, o ConvertOptions, source, target decimal.Decimal, from, to string, unit decimal.Decimal) (*ConversionResult, error) {
+	sufficient := func(amount decimal.Decimal) (*ConversionResult, bool, error) {
+		result, err := c.convert(table, o, amount, from, to)
+		if err != nil {
+			if convErr, ok := err.(ConversionError); ok && convErr.Code == "FEE_EXCEEDS_AMOUNT" {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		return result, result.NetAmount.Amount.GreaterThanOrEqual(target), nil
+	}
+
+	result, ok, err := sufficient(source)
+	for steps := 0; !ok; steps++ {
+		if err != nil {
+			return nil, err
+		}
+		if steps == maxReverseSteps {
+			return nil, ConversionError{
+				Code:    "UNREACHABLE_AMOUNT",
+				Message: fmt.Sprintf("no %s amount converts to %s %s", from, target, to),
+				Amount:  target.String(),
+				From:    from,
+				To:      to,
+			}
+		}
+		source = source.Add(unit)
+		result, ok, err = sufficient(source)
+	}
+
+	for steps := 0; steps < maxReverseSteps && source.GreaterThan(unit); steps++ {
+		smaller, ok, err := sufficient(source.Sub(unit))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		source, result = source.Sub(unit), smaller
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestConvertTo(t *testing.T) {
+	table := RateTable{}
+	table.Set("USD", "EUR", RateEntry{Rate: decimal.RequireFromString("0.5"), Source: SourceManual})
+	table.Set("USD", "JPY", RateEntry{Rate: decimal.RequireFromString("149.875"), Source: SourceManual})
+	table.Set("JPY", "USD", RateEntry{Rate: decimal.RequireFromString("0.006672"), Source: SourceManual})
+	conv := NewCurrencyConverter(WithRateProvider(NewMemoryRateProvider(table)))
+
+	tests := []struct {
+		target, from, to string
+		source, net      string
+	}{
+		{"1", "USD", "EUR", "1.99", "1.00"},    // estimated exactly as 2, then 0.995 rounds up
+		{"1.01", "USD", "EUR", "2.02", "1.01"}, // 2.01 USD is 1.005, which rounds to even
+		{"1000", "USD", "JPY", "6.67", "1000"}, // 6.66 USD is 998.1675 JPY
+		{"10", "JPY", "USD", "1499", "10.00"},  // 1498 JPY is 9.99 USD
+	}
+	for _, tt := range tests {
+		result, err := conv.ConvertTo(decimal.RequireFromString(tt.target), tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("ConvertTo(%s %s from %s): %v", tt.target, tt.to, tt.from, err)
+		}
+		if got := result.OriginalAmount.AmountString(); got != tt.source {
+			t.Errorf("ConvertTo(%s %s from %s) source = %s, want %s", tt.target, tt.to, tt.from, got, tt.source)
+		}
+		if got := result.NetAmount.AmountString(); got != tt.net {
+			t.Errorf("ConvertTo(%s %s from %s) net = %s, want %s", tt.target, tt.to, tt.from, got, tt.net)
+		}
+	}
+
+	if _, err := conv.ConvertTo(decimal.Zero, "USD", "EUR"); err == nil {
+		t.Error("ConvertTo accepted a zero target")
+	}
+}
+
+func TestConvertToRegisteredMinorUnits(t *testing.T) {
+	conv := NewCurrencyConverter()
+	if err := conv.AddCurrency(Currency{Code: "XAB", Name: "Test unit", MinorUnits: 4}); err != nil {
+		t.Fatalf("AddCurrency: %v", err)
+	}
+	if err := conv.SetExchangeRate("XAB", "USD", decimal.RequireFromString("0.5")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+
+	result, err := conv.ConvertTo(decimal.NewFromInt(1), "XAB", "USD")
+	if err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	if got := result.OriginalAmount.AmountString(); got != "1.9900" {
+		t.Errorf("source = %s, want 1.9900", got)
+	}
+}
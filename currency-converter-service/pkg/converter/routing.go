@@ -0,0 +1,177 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultPivotCurrency is the currency used to triangulate missing pairs
+const DefaultPivotCurrency = "USD"
+
+// RouteKind describes how an exchange rate was obtained
+type RouteKind string
+
+const (
+	// RouteDirect means the pair has its own entry in the rate table
+	RouteDirect RouteKind = "direct"
+	// RouteInverse means the rate is the reciprocal of the opposite pair
+	RouteInverse RouteKind = "inverse"
+	// RoutePivot means the rate was derived through the pivot currency
+	RoutePivot RouteKind = "pivot"
+	// RouteGraph means the rate was derived through the shortest path in the rate graph
+	RouteGraph RouteKind = "graph"
+)
+
+// Route is the chain of currencies used to derive an exchange rate
+type Route struct {
+	Kind       RouteKind
+	Currencies []string
+}
+
+// findRoute resolves the rate between two currencies, trying a direct entry
+// first, then the inverse of the opposite pair, then the pivot currency, and
+// finally the shortest path in the rate graph
+func (t RateTable) findRoute(from, to, pivot string) (decimal.Decimal, Route, error) {
+	if rate, ok := t.Rate(from, to); ok {
+		return rate, Route{Kind: RouteDirect, Currencies: []string{from, to}}, nil
+	}
+	if rate, ok := t.Rate(to, from); ok {
+		return inverseRate(rate), Route{Kind: RouteInverse, Currencies: []string{from, to}}, nil
+	}
+
+	if pivot != "" && pivot != from && pivot != to {
+		toPivot, ok1 := t.edgeRate(from, pivot)
+		fromPivot, ok2 := t.edgeRate(pivot, to)
+		if ok1 && ok2 {
+			return toPivot.Mul(fromPivot).Round(RatePrecision), Route{Kind: RoutePivot, Currencies: []string{from, pivot, to}}, nil
+		}
+	}
+
+	if path := t.shortestPath(from, to); path != nil {
+		rate := decimal.NewFromInt(1)
+		for i := 0; i < len(path)-1; i++ {
+			hop, _ := t.edgeRate(path[i], path[i+1])
+			rate = rate.Mul(hop)
+		}
+		return rate.Round(RatePrecision), Route{Kind: RouteGraph, Currencies: path}, nil
+	}
+
+	return decimal.Zero, Route{}, ConversionError{
+		Code:    "RATE_NOT_FOUND",
+		Message: fmt.Sprintf("exchange rate not found for %s to %s", from, to),
+		From:    from,
+		To:      to,
+	}
+}
+
+// edgeRate returns the rate for a single hop, falling back to the inverse of
+// the opposite pair so that every stored rate can be walked in both directions
+func (t RateTable) edgeRate(from, to string) (decimal.Decimal, bool) {
+	if rate, ok := t.Rate(from, to); ok {
+		return rate, true
+	}
+	if rate, ok := t.Rate(to, from); ok {
+		return inverseRate(rate), true
+	}
+	return decimal.Zero, false
+}
+
+// routeUpdatedAt returns the update time of the oldest rate along a route, so
+// a derived rate is never reported as fresher than the rates it came from
+func (t RateTable) routeUpdatedAt(route Route) time.Time {
+	var oldest time.Time
+	for i := 0; i < len(route.Currencies)-1; i++ {
+		entry, ok := t.Entry(route.Currencies[i], route.Currencies[i+1])
+		if !ok {
+			entry, _ = t.Entry(route.Currencies[i+1], route.Currencies[i])
+		}
+		if i == 0 || entry.UpdatedAt.Before(oldest) {
+			oldest = entry.UpdatedAt
+		}
+	}
+	return oldest
+}
+
+// routeSource returns the source of a direct route's stored rate, or
+// SourceDerived for rates computed from other pairs
+func (t RateTable) routeSource(route Route) string {
+	if route.Kind != RouteDirect {
+		return SourceDerived
+	}
+	entry, _ := t.Entry(route.Currencies[0], route.Currencies[1])
+	return entry.Source
+}
+
+// inverseRate returns 1/rate rounded to RatePrecision
+func inverseRate(rate decimal.Decimal) decimal.Decimal {
+	return decimal.NewFromInt(1).DivRound(rate, RatePrecision)
+}
+
+// shortestPath runs a breadth-first search over the rate graph and returns
+// the path with the fewest hops, or nil if the currencies are not connected
+func (t RateTable) shortestPath(from, to string) []string {
+	graph := t.adjacency()
+	if graph[from] == nil || graph[to] == nil {
+		return nil
+	}
+
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		// visit neighbours in a stable order so the same table always yields the same route
+		neighbours := make([]string, 0, len(graph[current]))
+		for next := range graph[current] {
+			neighbours = append(neighbours, next)
+		}
+		sort.Strings(neighbours)
+
+		for _, next := range neighbours {
+			if _, seen := previous[next]; seen {
+				continue
+			}
+			previous[next] = current
+			if next == to {
+				return buildPath(previous, from, to)
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	return nil
+}
+
+// adjacency returns the rate graph with every stored pair as an edge in both
+// directions
+func (t RateTable) adjacency() map[string]map[string]bool {
+	graph := make(map[string]map[string]bool)
+	for base, toRates := range t {
+		for quote := range toRates {
+			if graph[base] == nil {
+				graph[base] = make(map[string]bool)
+			}
+			if graph[quote] == nil {
+				graph[quote] = make(map[string]bool)
+			}
+			graph[base][quote] = true
+			graph[quote][base] = true
+		}
+	}
+	return graph
+}
+
+// buildPath walks the predecessor map back from the destination
+func buildPath(previous map[string]string, from, to string) []string {
+	path := []string{to}
+	for current := to; current != from; {
+		current = previous[current]
+		path = append([]string{current}, path...)
+	}
+	return path
+}
@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// routingTable links EUR-USD-GBP-JPY in a chain with rates whose inverses
+// are exact, plus CHF/XAU apart from the rest
+func routingTable() RateTable {
+	table := make(RateTable)
+	for _, r := range []struct{ from, to, rate string }{
+		{"USD", "EUR", "0.8"},
+		{"GBP", "USD", "1.25"},
+		{"JPY", "GBP", "0.005"},
+		{"CHF", "XAU", "0.0004"},
+	} {
+		table.Set(r.from, r.to, RateEntry{Rate: decimal.RequireFromString(r.rate), Source: SourceManual})
+	}
+	return table
+}
+
+func TestFindRoute(t *testing.T) {
+	table := routingTable()
+
+	tests := []struct {
+		from, to, pivot string
+		kind            RouteKind
+		route, rate     string
+	}{
+		{"USD", "EUR", "USD", RouteDirect, "USD,EUR", "0.8"},
+		{"EUR", "USD", "USD", RouteInverse, "EUR,USD", "1.25"},
+		{"EUR", "GBP", "USD", RoutePivot, "EUR,USD,GBP", "1"},
+		{"EUR", "GBP", "", RouteGraph, "EUR,USD,GBP", "1"},
+		{"EUR", "JPY", "USD", RouteGraph, "EUR,USD,GBP,JPY", "200"},
+		{"JPY", "EUR", "USD", RouteGraph, "JPY,GBP,USD,EUR", "0.005"},
+		{"XAU", "CHF", "USD", RouteInverse, "XAU,CHF", "2500"},
+	}
+	for _, tt := range tests {
+		rate, route, err := table.findRoute(tt.from, tt.to, tt.pivot)
+		if err != nil {
+			t.Errorf("%s to %s: %v", tt.from, tt.to, err)
+			continue
+		}
+		if route.Kind != tt.kind || strings.Join(route.Currencies, ",") != tt.route || !rate.Equal(decimal.RequireFromString(tt.rate)) {
+			t.Errorf("%s to %s with pivot %q = %s over %s %v, want %s over %s %s",
+				tt.from, tt.to, tt.pivot, rate, route.Kind, route.Currencies, tt.rate, tt.kind, tt.route)
+		}
+	}
+
+	for _, pair := range []Pair{{From: "EUR", To: "CHF"}, {From: "EUR", To: "SEK"}} {
+		_, _, err := table.findRoute(pair.From, pair.To, "USD")
+		if convErr, ok := err.(ConversionError); !ok || convErr.Code != "RATE_NOT_FOUND" {
+			t.Errorf("%s: err = %v, want RATE_NOT_FOUND", pair, err)
+		}
+	}
+}
+
+func TestShortestPathPrefersFewestHopsAndIsStable(t *testing.T) {
+	table := routingTable()
+	// a second two-hop path next to EUR-USD-GBP, and a shortcut to JPY
+	table.Set("EUR", "CAD", RateEntry{Rate: decimal.RequireFromString("1.5"), Source: SourceManual})
+	table.Set("CAD", "GBP", RateEntry{Rate: decimal.RequireFromString("0.55"), Source: SourceManual})
+	table.Set("CAD", "JPY", RateEntry{Rate: decimal.RequireFromString("110"), Source: SourceManual})
+
+	for i := 0; i < 20; i++ {
+		// ties go to the alphabetically first neighbour
+		if path := table.shortestPath("EUR", "GBP"); strings.Join(path, ",") != "EUR,CAD,GBP" {
+			t.Fatalf("EUR to GBP = %v, want EUR,CAD,GBP", path)
+		}
+	}
+	if path := table.shortestPath("EUR", "JPY"); strings.Join(path, ",") != "EUR,CAD,JPY" {
+		t.Errorf("EUR to JPY = %v, want EUR,CAD,JPY", path)
+	}
+	if path := table.shortestPath("EUR", "XAU"); path != nil {
+		t.Errorf("EUR to XAU = %v, want no path", path)
+	}
+}
+
+func TestConversionResultReportsRoute(t *testing.T) {
+	conv := NewCurrencyConverter(WithRateProvider(NewMemoryRateProvider(routingTable())))
+
+	result, err := conv.ConvertWithResult(decimal.NewFromInt(100), "EUR", "JPY")
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if result.RouteKind != RouteGraph || strings.Join(result.Route, ",") != "EUR,USD,GBP,JPY" ||
+		result.RateSource != SourceDerived || result.ConvertedAmount.String() != "20000 JPY" {
+		t.Errorf("EUR to JPY = %s over %s %v from %s", result.ConvertedAmount, result.RouteKind, result.Route, result.RateSource)
+	}
+
+	result, err = conv.ConvertWithResult(decimal.NewFromInt(100), "USD", "EUR")
+	if err != nil {
+		t.Fatalf("ConvertWithResult: %v", err)
+	}
+	if result.RouteKind != RouteDirect || result.RateSource != SourceManual || result.ConvertedAmount.String() != "80.00 EUR" {
+		t.Errorf("USD to EUR = %s over %s from %s", result.ConvertedAmount, result.RouteKind, result.RateSource)
+	}
+}
+
+// TestConvertThroughNewlySetPair sets a rate for a currency without any other
+// rate and converts from it to a third currency through the pivot
+func TestConvertThroughNewlySetPair(t *testing.T) {
+	conv := NewCurrencyConverter()
+	if _, err := conv.Convert(decimal.NewFromInt(100), "CHF", "EUR"); err == nil {
+		t.Fatal("CHF converted before any CHF rate was set")
+	}
+	if err := conv.SetExchangeRate("USD", "CHF", decimal.RequireFromString("0.9")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+
+	result, err := conv.ConvertWithResult(decimal.NewFromInt(100), "CHF", "EUR")
+	if err != nil {
+		t.Fatalf("CHF to EUR: %v", err)
+	}
+	// 100 / 0.9 USD at 0.85 EUR per USD
+	if result.RouteKind != RoutePivot || strings.Join(result.Route, ",") != "CHF,USD,EUR" || result.ConvertedAmount.String() != "94.44 EUR" {
+		t.Errorf("CHF to EUR = %s over %s %v, want 94.44 EUR over pivot CHF,USD,EUR",
+			result.ConvertedAmount, result.RouteKind, result.Route)
+	}
+
+	quote, err := conv.GetRateQuote("CHF", "EUR")
+	if err != nil || quote.RouteKind != RoutePivot || quote.Source != SourceDerived {
+		t.Errorf("GetRateQuote(CHF, EUR) = %+v, %v; want a derived pivot rate", quote, err)
+	}
+}
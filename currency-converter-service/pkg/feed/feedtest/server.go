@@ -0,0 +1,120 @@
+// Package feedtest provides a local rate feed that stands in for the real
+// ECB or openexchangerates endpoints in tests and local runs.
+package feedtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"currency-converter-service/pkg/feed"
+
+	"github.com/shopspring/decimal"
+)
+
+// Server serves a fixed set of quotes in a feed format. The quotes can be
+// changed and failures injected while it runs.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	format   feed.Format
+	quotes   feed.Quotes
+	failures int
+	requests int
+}
+
+// NewServer starts a fixture feed serving the given quotes
+func NewServer(format feed.Format, quotes feed.Quotes) *Server {
+	s := &Server{format: format, quotes: quotes}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// SetQuotes replaces the quotes served from now on
+func (s *Server) SetQuotes(quotes feed.Quotes) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotes = quotes
+}
+
+// FailNext makes the next n requests answer 503 Service Unavailable
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Requests returns the number of requests served so far, failed ones included
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "feed unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch s.format {
+	case feed.FormatECB:
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, ECBDocument(s.quotes))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, JSONDocument(s.quotes))
+	}
+}
+
+// ECBDocument renders quotes as an eurofxref-daily.xml document. The base is
+// always EUR in the real feed; quotes.Base is ignored.
+func ECBDocument(quotes feed.Quotes) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">` + "\n")
+	b.WriteString("\t<gesmes:subject>Reference rates</gesmes:subject>\n")
+	b.WriteString("\t<Cube>\n")
+	fmt.Fprintf(&b, "\t\t<Cube time=\"%s\">\n", asOf(quotes).Format("2006-01-02"))
+	for _, code := range codes(quotes.Rates) {
+		fmt.Fprintf(&b, "\t\t\t<Cube currency=\"%s\" rate=\"%s\"/>\n", code, quotes.Rates[code])
+	}
+	b.WriteString("\t\t</Cube>\n\t</Cube>\n</gesmes:Envelope>\n")
+	return b.String()
+}
+
+// JSONDocument renders quotes in the openexchangerates latest.json shape
+func JSONDocument(quotes feed.Quotes) string {
+	var rates []string
+	for _, code := range codes(quotes.Rates) {
+		rates = append(rates, fmt.Sprintf("%q: %s", code, quotes.Rates[code]))
+	}
+	return fmt.Sprintf("{\"base\": %q, \"timestamp\": %d, \"rates\": {%s}}\n",
+		quotes.Base, asOf(quotes).Unix(), strings.Join(rates, ", "))
+}
+
+func asOf(quotes feed.Quotes) time.Time {
+	if quotes.AsOf.IsZero() {
+		return time.Now().UTC()
+	}
+	return quotes.AsOf
+}
+
+func codes(rates map[string]decimal.Decimal) []string {
+	list := make([]string, 0, len(rates))
+	for code := range rates {
+		list = append(list, code)
+	}
+	sort.Strings(list)
+	return list
+}
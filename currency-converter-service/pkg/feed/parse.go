@@ -0,0 +1,157 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format is the wire format of a rate feed
+type Format string
+
+// Supported feed formats
+const (
+	// FormatECB is the European Central Bank eurofxref XML, quoted against EUR
+	FormatECB Format = "ecb"
+	// FormatJSON is the openexchangerates-style JSON document
+	// {"base": "USD", "timestamp": 1700000000, "rates": {"EUR": 0.92}}
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses a format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatECB, "xml":
+		return FormatECB, nil
+	case FormatJSON, "openexchangerates":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown feed format %q (want %s or %s)", name, FormatECB, FormatJSON)
+}
+
+// Quotes is one published set of rates: how many units of each quote
+// currency one unit of Base buys, as of a point in time
+type Quotes struct {
+	Base  string
+	AsOf  time.Time
+	Rates map[string]decimal.Decimal
+}
+
+// Parse decodes a feed document in the given format
+func Parse(format Format, body []byte) (*Quotes, error) {
+	switch format {
+	case FormatECB:
+		return parseECB(body)
+	case FormatJSON:
+		return parseJSON(body)
+	}
+	return nil, fmt.Errorf("unknown feed format %q", format)
+}
+
+// ecbEnvelope mirrors the eurofxref XML. Element names are matched without
+// their namespace, so the gesmes envelope needs no special handling.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// parseECB reads the most recent day of an ECB document
+func parseECB(body []byte) (*Quotes, error) {
+	var envelope ecbEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid ECB document: %w", err)
+	}
+	if len(envelope.Days) == 0 {
+		return nil, fmt.Errorf("ECB document contains no rates")
+	}
+
+	// daily and historical files list the newest day first
+	day := envelope.Days[0]
+	asOf, err := time.Parse("2006-01-02", day.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECB date %q", day.Time)
+	}
+
+	quotes := &Quotes{Base: "EUR", AsOf: asOf, Rates: make(map[string]decimal.Decimal)}
+	for _, rate := range day.Rates {
+		value, err := decimal.NewFromString(rate.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB rate %q for %s", rate.Rate, rate.Currency)
+		}
+		quotes.Rates[strings.ToUpper(rate.Currency)] = value
+	}
+	return quotes, nil
+}
+
+// jsonDocument mirrors the openexchangerates latest.json shape
+type jsonDocument struct {
+	Base      string                     `json:"base"`
+	Timestamp int64                      `json:"timestamp"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+func parseJSON(body []byte) (*Quotes, error) {
+	var doc jsonDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON rate document: %w", err)
+	}
+
+	quotes := &Quotes{Base: strings.ToUpper(doc.Base), Rates: make(map[string]decimal.Decimal)}
+	if doc.Timestamp > 0 {
+		quotes.AsOf = time.Unix(doc.Timestamp, 0).UTC()
+	}
+	for code, rate := range doc.Rates {
+		quotes.Rates[strings.ToUpper(code)] = rate
+	}
+	return quotes, nil
+}
+
+// Table validates the quotes and turns them into a rate table recorded under
+// the given source. Every quote is stored in both directions, the opposite one
+// as its inverse, so a rate set before the feed never outlives it in one
+// direction. Quote currencies outside ISO 4217 are skipped and returned, as
+// feeds commonly carry crypto or fund codes. A zero AsOf takes effect now; an
+// AsOf in the future is clamped to now.
+func (q *Quotes) Table(source string) (converter.RateTable, []string, error) {
+	if _, ok := converter.LookupISO4217(q.Base); !ok {
+		return nil, nil, fmt.Errorf("feed base currency %q is not an ISO 4217 code", q.Base)
+	}
+
+	asOf := q.AsOf
+	if now := time.Now(); asOf.After(now) {
+		asOf = now
+	}
+
+	table := make(converter.RateTable)
+	var skipped []string
+	for code, rate := range q.Rates {
+		if code == q.Base {
+			continue
+		}
+		if _, ok := converter.LookupISO4217(code); !ok {
+			skipped = append(skipped, code)
+			continue
+		}
+		if rate.Sign() <= 0 {
+			return nil, nil, fmt.Errorf("feed rate for %s/%s must be greater than zero, got %s", q.Base, code, rate)
+		}
+		table.Set(q.Base, code, converter.RateEntry{Rate: rate, Source: source, UpdatedAt: asOf})
+		inverse := decimal.NewFromInt(1).DivRound(rate, converter.RatePrecision)
+		table.Set(code, q.Base, converter.RateEntry{Rate: inverse, Source: source, UpdatedAt: asOf})
+	}
+	if len(table) == 0 {
+		return nil, nil, fmt.Errorf("feed contains no usable rates")
+	}
+	return table, skipped, nil
+}
@@ -0,0 +1,253 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/converter"
+)
+
+// FailurePolicy decides what happens to feed rates once a refresh has failed
+// after all retries
+type FailurePolicy string
+
+// Supported failure policies
+const (
+	// KeepLastGood leaves the rates of the last successful refresh in place
+	KeepLastGood FailurePolicy = "keep"
+	// DropRates removes the feed's rates so conversions fall back to the
+	// remaining manual and default rates instead of using stale data
+	DropRates FailurePolicy = "drop"
+)
+
+// ParseFailurePolicy parses a failure policy name
+func ParseFailurePolicy(name string) (FailurePolicy, error) {
+	switch FailurePolicy(name) {
+	case KeepLastGood, "keep-last-good":
+		return KeepLastGood, nil
+	case DropRates:
+		return DropRates, nil
+	}
+	return "", fmt.Errorf("unknown feed failure policy %q (want %s or %s)", name, KeepLastGood, DropRates)
+}
+
+// Config describes where a feed lives and how it is refreshed
+type Config struct {
+	URL    string
+	Format Format
+	// Source is recorded with every imported rate; defaults to converter.SourceFeed
+	Source string
+	// Interval between scheduled refreshes; defaults to one hour
+	Interval time.Duration
+	// Retries is the number of extra attempts after a failed fetch
+	Retries int
+	// Backoff is the wait before the first retry, doubled for every further
+	// retry up to MaxBackoff; defaults to one second and one minute
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// OnFailure defaults to KeepLastGood
+	OnFailure FailurePolicy
+	// Timeout bounds a single HTTP request; defaults to 30 seconds
+	Timeout time.Duration
+	// Audit records the rate changes of refreshes under the principal
+	// AuditPrincipal; nil disables auditing
+	Audit *audit.Log
+}
+
+// AuditPrincipal is the principal of rate changes made by the feed
+const AuditPrincipal = "feed"
+
+// Status reports the outcome of the most recent refreshes
+type Status struct {
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   string
+	Changed     int
+}
+
+// Refresher periodically pulls rates from a feed and imports them into a
+// converter
+type Refresher struct {
+	cfg    Config
+	conv   converter.ICurrencyConverter
+	client *http.Client
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewRefresher creates a refresher, filling in defaults for unset settings
+func NewRefresher(conv converter.ICurrencyConverter, cfg Config) (*Refresher, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("feed URL is required")
+	}
+	if _, err := ParseFormat(string(cfg.Format)); err != nil {
+		return nil, err
+	}
+	if cfg.Source == "" {
+		cfg.Source = converter.SourceFeed
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.Backoff {
+		cfg.MaxBackoff = time.Minute
+		if cfg.MaxBackoff < cfg.Backoff {
+			cfg.MaxBackoff = cfg.Backoff
+		}
+	}
+	if cfg.OnFailure == "" {
+		cfg.OnFailure = KeepLastGood
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	return &Refresher{
+		cfg:    cfg,
+		conv:   conv,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Run refreshes immediately and then on every interval until ctx is done
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("rate feed refresh failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the feed, retrying with backoff, and imports the rates. If
+// every attempt fails the failure policy is applied and the last error returned.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	var table converter.RateTable
+	var err error
+
+	backoff := r.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		table, err = r.fetch(ctx)
+		if err == nil || attempt >= r.cfg.Retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > r.cfg.MaxBackoff {
+			backoff = r.cfg.MaxBackoff
+		}
+	}
+
+	var diffs []converter.RateDiff
+	if err == nil {
+		change := audit.Change{Operation: audit.OpImport, Principal: AuditPrincipal, Reason: "scheduled refresh from " + r.cfg.URL}
+		diffs, err = r.cfg.Audit.Apply(r.conv, change, func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+			return conv.ImportRates(table)
+		})
+	}
+	if err != nil {
+		r.fail(err)
+		return err
+	}
+
+	r.mu.Lock()
+	r.status.LastAttempt = time.Now()
+	r.status.LastSuccess = r.status.LastAttempt
+	r.status.LastError = ""
+	r.status.Changed = len(diffs)
+	r.mu.Unlock()
+
+	log.Printf("rate feed refreshed: %d rates changed", len(diffs))
+	return nil
+}
+
+// fail records a failed refresh and applies the failure policy
+func (r *Refresher) fail(err error) {
+	r.mu.Lock()
+	r.status.LastAttempt = time.Now()
+	r.status.LastError = err.Error()
+	r.status.Changed = 0
+	r.mu.Unlock()
+
+	if r.cfg.OnFailure != DropRates {
+		return
+	}
+	change := audit.Change{Operation: audit.OpRemoveSource, Principal: AuditPrincipal, Reason: "refresh failed: " + err.Error()}
+	diffs, dropErr := r.cfg.Audit.Apply(r.conv, change, func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.RemoveRatesFrom(r.cfg.Source)
+	})
+	if dropErr != nil {
+		log.Printf("rate feed: failed to drop feed rates: %v", dropErr)
+		return
+	}
+	if len(diffs) > 0 {
+		log.Printf("rate feed: dropped %d feed rates after failed refresh", len(diffs))
+	}
+}
+
+// fetch downloads and parses the feed once
+func (r *Refresher) fetch(ctx context.Context) (converter.RateTable, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := Parse(r.cfg.Format, body)
+	if err != nil {
+		return nil, err
+	}
+	table, skipped, err := quotes.Table(r.cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		log.Printf("rate feed: skipped %d non-ISO 4217 currencies", len(skipped))
+	}
+	return table, nil
+}
+
+// Status returns the outcome of the most recent refresh
+func (r *Refresher) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
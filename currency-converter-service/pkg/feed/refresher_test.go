@@ -0,0 +1,172 @@
+package feed_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/feed"
+	"currency-converter-service/pkg/feed/feedtest"
+
+	"github.com/shopspring/decimal"
+)
+
+func ecbQuotes(usd string) feed.Quotes {
+	return feed.Quotes{
+		Base: "EUR",
+		AsOf: time.Now().UTC().Truncate(24 * time.Hour),
+		Rates: map[string]decimal.Decimal{
+			"USD": decimal.RequireFromString(usd),
+			"GBP": decimal.RequireFromString("0.86"),
+			"BTC": decimal.RequireFromString("0.000016"),
+		},
+	}
+}
+
+func newRefresher(t *testing.T, conv converter.ICurrencyConverter, server *feedtest.Server, cfg feed.Config) *feed.Refresher {
+	t.Helper()
+	cfg.URL = server.URL
+	if cfg.Format == "" {
+		cfg.Format = feed.FormatECB
+	}
+	cfg.Backoff = time.Millisecond
+	refresher, err := feed.NewRefresher(conv, cfg)
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	return refresher
+}
+
+// TestRefreshReplacesBothDirections checks that a feed rate also replaces the
+// default rate of the opposite direction, whatever the inverse policy
+func TestRefreshReplacesBothDirections(t *testing.T) {
+	server := feedtest.NewServer(feed.FormatECB, ecbQuotes("1.10"))
+	defer server.Close()
+
+	for _, policy := range []converter.InversePolicy{converter.InverseIndependent, converter.InverseDerive, converter.InverseReject} {
+		conv := converter.NewCurrencyConverter(converter.WithInversePolicy(policy, decimal.Zero))
+		if err := newRefresher(t, conv, server, feed.Config{}).Refresh(context.Background()); err != nil {
+			t.Fatalf("%s: Refresh: %v", policy, err)
+		}
+
+		usd, err := conv.Convert(decimal.NewFromInt(100), "EUR", "USD")
+		if err != nil {
+			t.Fatalf("%s: Convert EUR to USD: %v", policy, err)
+		}
+		eur, err := conv.Convert(usd.Amount, "USD", "EUR")
+		if err != nil {
+			t.Fatalf("%s: Convert USD to EUR: %v", policy, err)
+		}
+		if usd.String() != "110.00 USD" || eur.String() != "100.00 EUR" {
+			t.Errorf("%s: 100 EUR -> %s -> %s, want 110.00 USD -> 100.00 EUR", policy, usd, eur)
+		}
+
+		quote, err := conv.GetRateQuote("USD", "EUR")
+		if err != nil {
+			t.Fatalf("%s: GetRateQuote: %v", policy, err)
+		}
+		if quote.Source != converter.SourceFeed {
+			t.Errorf("%s: USD/EUR source = %s, want %s", policy, quote.Source, converter.SourceFeed)
+		}
+	}
+}
+
+func TestRefreshRetriesAndDropsOnFailure(t *testing.T) {
+	server := feedtest.NewServer(feed.FormatECB, ecbQuotes("1.10"))
+	defer server.Close()
+
+	conv := converter.NewCurrencyConverter()
+	refresher := newRefresher(t, conv, server, feed.Config{Retries: 2, OnFailure: feed.DropRates})
+
+	server.FailNext(2)
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh after two failures: %v", err)
+	}
+	if got := server.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	if status := refresher.Status(); status.LastError != "" || status.Changed == 0 {
+		t.Errorf("status after success = %+v", status)
+	}
+
+	server.FailNext(3)
+	if err := refresher.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh succeeded with the feed down")
+	}
+	for _, pair := range []converter.Pair{{From: "EUR", To: "USD"}, {From: "USD", To: "EUR"}} {
+		quote, err := conv.GetRateQuote(pair.From, pair.To)
+		if err == nil && quote.Source == converter.SourceFeed {
+			t.Errorf("%s still served from the feed after it was dropped", pair)
+		}
+	}
+}
+
+func TestQuotesTableSkipsNonISOCodes(t *testing.T) {
+	quotes, err := feed.Parse(feed.FormatECB, []byte(feedtest.ECBDocument(ecbQuotes("1.25"))))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	table, skipped, err := quotes.Table(converter.SourceFeed)
+	if err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0] != "BTC" {
+		t.Errorf("skipped = %v, want [BTC]", skipped)
+	}
+	if rate, ok := table.Rate("USD", "EUR"); !ok || !rate.Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("USD/EUR = %s, %v; want 0.8", rate, ok)
+	}
+
+	quotes.Rates["GBP"] = decimal.Zero
+	if _, _, err := quotes.Table(converter.SourceFeed); err == nil {
+		t.Error("Table accepted a zero rate")
+	}
+}
+
+// TestRefreshBehindNewerManualRate imports feed rates dated before a manual
+// rate; the manual rate stays in effect and repeated refreshes change nothing
+func TestRefreshBehindNewerManualRate(t *testing.T) {
+	quotes := ecbQuotes("1.10")
+	quotes.AsOf = time.Now().Add(-time.Hour).Truncate(time.Second)
+	server := feedtest.NewServer(feed.FormatJSON, quotes)
+	defer server.Close()
+
+	conv := converter.NewCurrencyConverter()
+	if err := conv.SetExchangeRate("EUR", "USD", decimal.RequireFromString("1.20")); err != nil {
+		t.Fatalf("SetExchangeRate: %v", err)
+	}
+	refresher := newRefresher(t, conv, server, feed.Config{Format: feed.FormatJSON})
+
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := refresher.Status().Changed; got != 3 {
+		t.Errorf("first refresh changed %d rates, want 3 (EUR/GBP and the inverses)", got)
+	}
+	history := conv.GetRateHistory("EUR", "USD", time.Time{}, time.Time{})
+
+	for i := 0; i < 3; i++ {
+		if err := refresher.Refresh(context.Background()); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+		if got := refresher.Status().Changed; got != 0 {
+			t.Errorf("refresh %d changed %d rates, want 0", i+2, got)
+		}
+	}
+
+	quote, err := conv.GetRateQuote("EUR", "USD")
+	if err != nil {
+		t.Fatalf("GetRateQuote: %v", err)
+	}
+	if !quote.Rate.Equal(decimal.RequireFromString("1.20")) || quote.Source != converter.SourceManual {
+		t.Errorf("EUR/USD = %s from %s, want the manual 1.20", quote.Rate, quote.Source)
+	}
+	if got := conv.GetRateHistory("EUR", "USD", time.Time{}, time.Time{}); len(got) != len(history) {
+		t.Errorf("history grew from %d to %d points", len(history), len(got))
+	}
+	rate, err := conv.ConvertAt(decimal.NewFromInt(100), "EUR", "USD", quotes.AsOf.Add(time.Minute))
+	if err != nil || rate.String() != "110.00 USD" {
+		t.Errorf("100 EUR as of the feed = %s, %v; want 110.00 USD", rate, err)
+	}
+}
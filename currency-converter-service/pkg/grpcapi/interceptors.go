@@ -0,0 +1,176 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/grpcapi/converterpb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies the service in the ErrorInfo details of errors
+const errorDomain = "currency-converter-service"
+
+// Config holds the settings of the gRPC API
+type Config struct {
+	// Principals maps API keys to their roles. Nil means no keys are accepted.
+	Principals *auth.Principals
+	// AnonymousRole is granted to calls without an API key; empty grants none
+	AnonymousRole auth.Role
+	// Logger receives one record per call; nil uses slog.Default()
+	Logger *slog.Logger
+	// Audit records the rate changes made through the API; nil disables it
+	Audit *audit.Log
+}
+
+func (c Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// methodRoles is the role each method requires, matching the REST routes
+var methodRoles = map[string]auth.Role{
+	converterpb.ConverterService_Convert_FullMethodName:        auth.RoleConverter,
+	converterpb.ConverterService_ConvertBatch_FullMethodName:   auth.RoleConverter,
+	converterpb.ConverterService_GetRate_FullMethodName:        auth.RoleViewer,
+	converterpb.ConverterService_SetRate_FullMethodName:        auth.RoleRateAdmin,
+	converterpb.ConverterService_ListCurrencies_FullMethodName: auth.RoleViewer,
+	converterpb.ConverterService_WatchRates_FullMethodName:     auth.RoleViewer,
+}
+
+// authorize resolves the caller from the "x-api-key" or "authorization:
+// Bearer <key>" metadata and checks it holds the method's role
+func authorize(ctx context.Context, cfg Config, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := first(md.Get("x-api-key"))
+	if value := first(md.Get("authorization")); key == "" && len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		key = strings.TrimSpace(value[7:])
+	}
+
+	principal := auth.Principal{Name: auth.Anonymous}
+	if cfg.AnonymousRole != "" {
+		principal.Roles = []auth.Role{cfg.AnonymousRole}
+	}
+	if key != "" {
+		var ok bool
+		if cfg.Principals == nil {
+			return nil, status.Error(codes.Unauthenticated, "unknown API key")
+		}
+		if principal, ok = cfg.Principals.Authenticate(key); !ok {
+			return nil, status.Error(codes.Unauthenticated, "unknown API key")
+		}
+	}
+
+	role, ok := methodRoles[method]
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "unknown method")
+	}
+	if !principal.Has(role) {
+		if principal.Name == auth.Anonymous {
+			return nil, status.Error(codes.Unauthenticated, "an API key is required")
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "requires the %s role", role)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func authenticateUnary(cfg Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, cfg, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authenticateStream(cfg Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), cfg, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// loggingUnary logs each call with the same fields as the HTTP access log
+func loggingUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func loggingStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	logger.LogAttrs(ctx, slog.LevelInfo, "rpc",
+		slog.String("request_id", first(md.Get("x-request-id"))),
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+	)
+}
+
+// statusError converts a converter error. ConversionErrors get the given code
+// and carry their error code as the ErrorInfo reason.
+func statusError(err error, code codes.Code) error {
+	var convErr converter.ConversionError
+	if !errors.As(err, &convErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	st, detailErr := status.New(code, convErr.Message).WithDetails(&errdetails.ErrorInfo{
+		Reason: convErr.Code,
+		Domain: errorDomain,
+	})
+	if detailErr != nil {
+		return status.Error(code, convErr.Message)
+	}
+	return st.Err()
+}
+
+// invalidArgument reports a malformed request field with an error code
+func invalidArgument(reason, message string) error {
+	return statusError(converter.ConversionError{Code: reason, Message: message}, codes.InvalidArgument)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,327 @@
+// Package grpcapi serves the converter over gRPC, backed by the same
+// ICurrencyConverter as the REST API. The service definition is
+// proto/converter/v1/converter.proto; run `make proto` after changing it.
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/grpcapi/converterpb"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many rate changes a WatchRates stream may fall behind
+// before it is disconnected
+const watchBuffer = 256
+
+// Server implements converterpb.ConverterServiceServer
+type Server struct {
+	converterpb.UnimplementedConverterServiceServer
+	converter converter.ICurrencyConverter
+	auditLog  *audit.Log
+}
+
+// NewServer creates a gRPC server with the converter service registered and
+// the authentication and logging interceptors installed
+func NewServer(conv converter.ICurrencyConverter, cfg Config) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(loggingUnary(cfg.logger()), authenticateUnary(cfg)),
+		grpc.ChainStreamInterceptor(loggingStream(cfg.logger()), authenticateStream(cfg)),
+	)
+	converterpb.RegisterConverterServiceServer(server, &Server{converter: conv, auditLog: cfg.Audit})
+	return server
+}
+
+// Convert converts an amount
+func (s *Server) Convert(ctx context.Context, req *converterpb.ConvertRequest) (*converterpb.Conversion, error) {
+	amount, err := parseConversion(req.GetAmount(), req.GetFrom(), req.GetTo())
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+	opts, err := convertOptions(req.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.converter.ConvertWithResult(amount, req.GetFrom(), req.GetTo(), opts...)
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+	return conversion(result), nil
+}
+
+// ConvertBatch converts every item against one rate snapshot
+func (s *Server) ConvertBatch(ctx context.Context, req *converterpb.ConvertBatchRequest) (*converterpb.ConvertBatchResponse, error) {
+	opts, err := convertOptions(req.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	// items are parsed one by one, so an invalid item fails on its own
+	// instead of rejecting the batch
+	response := &converterpb.ConvertBatchResponse{Results: make([]*converterpb.BatchResult, len(req.GetItems()))}
+	var items []converter.BatchItem
+	var positions []int
+	for i, item := range req.GetItems() {
+		response.Results[i] = &converterpb.BatchResult{Id: item.GetId()}
+		amount, err := parseConversion(item.GetAmount(), item.GetFrom(), item.GetTo())
+		if err != nil {
+			response.Results[i].Outcome = batchError(err.(converter.ConversionError))
+			continue
+		}
+		items = append(items, converter.BatchItem{ID: item.GetId(), Amount: amount, From: item.GetFrom(), To: item.GetTo()})
+		positions = append(positions, i)
+	}
+	// an empty batch is still left to the converter to reject
+	if len(items) == 0 && len(req.GetItems()) > 0 {
+		return response, nil
+	}
+
+	results, err := s.converter.ConvertBatch(items, opts...)
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+
+	for i, result := range results {
+		entry := response.Results[positions[i]]
+		if result.Error != nil {
+			entry.Outcome = batchError(*result.Error)
+		} else {
+			entry.Outcome = &converterpb.BatchResult_Conversion{Conversion: conversion(result.Result)}
+		}
+	}
+	return response, nil
+}
+
+// batchError converts the failure of one batch item
+func batchError(err converter.ConversionError) *converterpb.BatchResult_Error {
+	return &converterpb.BatchResult_Error{Error: &converterpb.Error{Code: err.Code, Message: err.Message}}
+}
+
+// GetRate returns the rate of a pair
+func (s *Server) GetRate(ctx context.Context, req *converterpb.GetRateRequest) (*converterpb.Rate, error) {
+	if err := checkPair(req.GetFrom(), req.GetTo()); err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+	quote, err := s.converter.GetRateQuote(req.GetFrom(), req.GetTo())
+	if err != nil {
+		return nil, statusError(err, codes.NotFound)
+	}
+	return &converterpb.Rate{
+		From:      quote.From,
+		To:        quote.To,
+		Rate:      quote.Rate.String(),
+		Source:    quote.Source,
+		UpdatedAt: timestamp(quote.UpdatedAt),
+		RouteKind: string(quote.RouteKind),
+		Route:     quote.Route,
+	}, nil
+}
+
+// SetRate stores a rate, effective now unless backdated
+func (s *Server) SetRate(ctx context.Context, req *converterpb.SetRateRequest) (*converterpb.SetRateResponse, error) {
+	if err := checkPair(req.GetFrom(), req.GetTo()); err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+	rate, err := parseDecimal("rate", req.GetRate())
+	if err != nil {
+		return nil, err
+	}
+	effective := time.Now()
+	if req.GetEffectiveAt() != nil {
+		effective = req.GetEffectiveAt().AsTime()
+	}
+
+	_, err = s.auditLog.Apply(s.converter, s.auditChange(ctx, audit.OpSet, req.GetReason()), func(conv converter.ICurrencyConverter) ([]converter.RateDiff, error) {
+		return conv.SetExchangeRateAt(req.GetFrom(), req.GetTo(), rate, effective)
+	})
+	if err != nil {
+		return nil, statusError(err, codes.InvalidArgument)
+	}
+	return &converterpb.SetRateResponse{}, nil
+}
+
+// auditChange attributes a change to the principal and request ID of a call
+func (s *Server) auditChange(ctx context.Context, operation, reason string) audit.Change {
+	principal, _ := auth.PrincipalFrom(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+	return audit.Change{
+		Operation: operation,
+		Principal: principal.Name,
+		RequestID: first(md.Get("x-request-id")),
+		Reason:    reason,
+	}
+}
+
+// ListCurrencies lists the registered currencies
+func (s *Server) ListCurrencies(ctx context.Context, req *converterpb.ListCurrenciesRequest) (*converterpb.ListCurrenciesResponse, error) {
+	response := &converterpb.ListCurrenciesResponse{}
+	for _, info := range s.converter.ListCurrencies() {
+		response.Currencies = append(response.Currencies, &converterpb.Currency{
+			Code:        info.Code,
+			NumericCode: info.NumericCode,
+			Name:        info.Name,
+			MinorUnits:  info.MinorUnits,
+			Symbol:      info.Symbol,
+			HasRates:    info.HasRates,
+		})
+	}
+	return response, nil
+}
+
+// WatchRates streams rate changes until the client cancels
+func (s *Server) WatchRates(req *converterpb.WatchRatesRequest, stream converterpb.ConverterService_WatchRatesServer) error {
+	filter := make(map[string]bool, len(req.GetCurrencies()))
+	for _, code := range req.GetCurrencies() {
+		filter[strings.ToUpper(code)] = true
+	}
+
+	events, cancel := s.converter.SubscribeRates(watchBuffer)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "rate stream fell behind; re-read the rates and watch again")
+			}
+			if len(filter) > 0 && !filter[event.Pair.From] && !filter[event.Pair.To] {
+				continue
+			}
+			if err := stream.Send(rateChange(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// convertOptions turns the request options into converter options
+func convertOptions(req *converterpb.ConversionOptions) ([]converter.ConvertOption, error) {
+	var opts []converter.ConvertOption
+	if req.GetAsOf() != nil {
+		opts = append(opts, converter.AsOf(req.GetAsOf().AsTime()))
+	}
+	if req.GetMaxAge() != nil {
+		maxAge := req.GetMaxAge().AsDuration()
+		if maxAge <= 0 {
+			return nil, invalidArgument("INVALID_MAX_AGE", "max_age must be positive")
+		}
+		opts = append(opts, converter.MaxAge(maxAge))
+	}
+	if req.GetProfile() != "" {
+		opts = append(opts, converter.WithProfile(req.GetProfile()))
+	}
+	if req.GetSide() != "" {
+		side, err := converter.ParseSide(req.GetSide())
+		if err != nil {
+			return nil, invalidArgument("INVALID_SIDE", err.Error())
+		}
+		opts = append(opts, converter.WithSide(side))
+	}
+	return opts, nil
+}
+
+// parseDecimal parses a string-encoded decimal field
+func parseDecimal(field, value string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, invalidArgument("INVALID_AMOUNT", field+" must be a decimal number such as \"12.50\"")
+	}
+	return d, nil
+}
+
+// parseConversion parses the amount and checks the currency codes of a
+// conversion, as the REST API validates its requests. It returns
+// ConversionErrors, so a batch can report them per item.
+func parseConversion(amount, from, to string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return decimal.Zero, converter.ConversionError{
+			Code:    "INVALID_AMOUNT",
+			Message: "amount must be a decimal number such as \"12.50\"",
+			From:    from,
+			To:      to,
+		}
+	}
+	return d, checkPair(from, to)
+}
+
+// checkPair checks that both currencies of a pair are three-letter codes
+func checkPair(from, to string) error {
+	for _, field := range []struct{ name, code string }{{"from", from}, {"to", to}} {
+		if len(field.code) != 3 || strings.IndexFunc(field.code, func(r rune) bool {
+			return (r < 'A' || r > 'Z') && (r < 'a' || r > 'z')
+		}) >= 0 {
+			return converter.ConversionError{
+				Code:    "INVALID_CURRENCY",
+				Message: field.name + " must be a three-letter currency code",
+				From:    from,
+				To:      to,
+			}
+		}
+	}
+	return nil
+}
+
+// conversion converts a conversion result for the API
+func conversion(result *converter.ConversionResult) *converterpb.Conversion {
+	response := &converterpb.Conversion{
+		ConvertedAmount: result.ConvertedAmount.AmountString(),
+		OriginalAmount:  result.OriginalAmount.AmountString(),
+		FromCurrency:    result.FromCurrency,
+		ToCurrency:      result.ToCurrency,
+		ExchangeRate:    result.ExchangeRate.String(),
+		MidRate:         result.MidRate.String(),
+		Profile:         result.Profile,
+		Side:            string(result.Side),
+		Fee:             result.Fee.AmountString(),
+		NetAmount:       result.NetAmount.AmountString(),
+		RouteKind:       string(result.RouteKind),
+		Route:           result.Route,
+		RateSource:      result.RateSource,
+		RateUpdatedAt:   timestamp(result.RateUpdatedAt),
+		Timestamp:       timestamppb.New(result.Timestamp),
+	}
+	if result.AsOf != nil {
+		response.AsOf = timestamppb.New(*result.AsOf)
+	}
+	return response
+}
+
+// rateChange converts a rate event for the API
+func rateChange(event converter.RateEvent) *converterpb.RateChange {
+	change := &converterpb.RateChange{
+		From:      event.Pair.From,
+		To:        event.Pair.To,
+		Source:    event.Source,
+		ChangedAt: timestamppb.New(event.At),
+	}
+	if !event.Previous.IsZero() {
+		change.PreviousRate = event.Previous.String()
+	}
+	if !event.Current.IsZero() {
+		change.CurrentRate = event.Current.String()
+	}
+	return change
+}
+
+// timestamp converts a time, leaving zero times unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,267 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/audit"
+	"currency-converter-service/pkg/auth"
+	"currency-converter-service/pkg/converter"
+	"currency-converter-service/pkg/grpcapi/converterpb"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the converter over an in-memory connection with three API keys
+// and returns a client for it
+func dial(t *testing.T, conv converter.ICurrencyConverter, cfg Config) converterpb.ConverterServiceClient {
+	t.Helper()
+	cfg.Principals = auth.NewPrincipals()
+	for key, principal := range map[string]auth.Principal{
+		"viewer-key":    {Name: "dashboard", Roles: []auth.Role{auth.RoleViewer}},
+		"converter-key": {Name: "checkout", Roles: []auth.Role{auth.RoleConverter}},
+		"admin-key":     {Name: "treasury", Roles: []auth.Role{auth.RoleRateAdmin}},
+	} {
+		if err := cfg.Principals.Add(auth.HashKey(key), principal); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(conv, cfg)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return converterpb.NewConverterServiceClient(conn)
+}
+
+// withKey attaches metadata to the context of a call
+func withKey(name, value string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), name, value)
+}
+
+// reason returns the error code in the ErrorInfo details of a status
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestMethodRoles(t *testing.T) {
+	client := dial(t, converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleViewer})
+	convert := func(ctx context.Context) error {
+		_, err := client.Convert(ctx, &converterpb.ConvertRequest{Amount: "10", From: "USD", To: "EUR"})
+		return err
+	}
+	setRate := func(ctx context.Context) error {
+		_, err := client.SetRate(ctx, &converterpb.SetRateRequest{From: "USD", To: "EUR", Rate: "0.9"})
+		return err
+	}
+	getRate := func(ctx context.Context) error {
+		_, err := client.GetRate(ctx, &converterpb.GetRateRequest{From: "USD", To: "EUR"})
+		return err
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(context.Context) error
+		want codes.Code
+	}{
+		{"anonymous reads rates", context.Background(), getRate, codes.OK},
+		{"anonymous converts", context.Background(), convert, codes.Unauthenticated},
+		{"unknown key", withKey("x-api-key", "stolen-key"), getRate, codes.Unauthenticated},
+		{"viewer converts", withKey("x-api-key", "viewer-key"), convert, codes.PermissionDenied},
+		{"converter converts", withKey("x-api-key", "converter-key"), convert, codes.OK},
+		{"converter sets a rate", withKey("x-api-key", "converter-key"), setRate, codes.PermissionDenied},
+		{"bearer admin sets a rate", withKey("authorization", "Bearer admin-key"), setRate, codes.OK},
+		{"admin converts", withKey("x-api-key", "admin-key"), convert, codes.OK},
+	}
+	for _, tt := range tests {
+		if got := status.Code(tt.call(tt.ctx)); got != tt.want {
+			t.Errorf("%s: code = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	client := dial(t, converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleConverter})
+	ctx := context.Background()
+
+	result, err := client.Convert(ctx, &converterpb.ConvertRequest{Amount: "100", From: "usd", To: "eur"})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	if result.ConvertedAmount != "85.00" || result.FromCurrency != "USD" || result.ToCurrency != "EUR" || result.ExchangeRate != "0.85" {
+		t.Errorf("100 USD to EUR = %+v", result)
+	}
+
+	errorTests := []struct {
+		name   string
+		req    *converterpb.ConvertRequest
+		code   codes.Code
+		reason string
+	}{
+		{"unparsable amount", &converterpb.ConvertRequest{Amount: "ten", From: "USD", To: "EUR"}, codes.InvalidArgument, "INVALID_AMOUNT"},
+		{"negative amount", &converterpb.ConvertRequest{Amount: "-1", From: "USD", To: "EUR"}, codes.InvalidArgument, "INVALID_AMOUNT"},
+		{"long code", &converterpb.ConvertRequest{Amount: "1", From: "USDT", To: "EUR"}, codes.InvalidArgument, "INVALID_CURRENCY"},
+		{"digits in the code", &converterpb.ConvertRequest{Amount: "1", From: "USD", To: "E1R"}, codes.InvalidArgument, "INVALID_CURRENCY"},
+		{"no rate", &converterpb.ConvertRequest{Amount: "1", From: "USD", To: "SEK"}, codes.InvalidArgument, "RATE_NOT_FOUND"},
+	}
+	for _, tt := range errorTests {
+		_, err := client.Convert(ctx, tt.req)
+		if status.Code(err) != tt.code || reason(err) != tt.reason {
+			t.Errorf("%s: err = %v (%s), want %s %s", tt.name, err, reason(err), tt.code, tt.reason)
+		}
+	}
+}
+
+func TestConvertBatchReportsInvalidItemsPerItem(t *testing.T) {
+	client := dial(t, converter.NewCurrencyConverter(), Config{AnonymousRole: auth.RoleConverter})
+
+	resp, err := client.ConvertBatch(context.Background(), &converterpb.ConvertBatchRequest{Items: []*converterpb.BatchItem{
+		{Id: "ok", Amount: "100", From: "USD", To: "EUR"},
+		{Id: "amount", Amount: "lots", From: "USD", To: "EUR"},
+		{Id: "code", Amount: "1", From: "US", To: "EUR"},
+		{Id: "rate", Amount: "1", From: "USD", To: "SEK"},
+		{Id: "last", Amount: "1", From: "USD", To: "JPY"},
+	}})
+	if err != nil {
+		t.Fatalf("ConvertBatch: %v", err)
+	}
+	want := []struct{ id, code, converted string }{
+		{"ok", "", "85.00"},
+		{"amount", "INVALID_AMOUNT", ""},
+		{"code", "INVALID_CURRENCY", ""},
+		{"rate", "RATE_NOT_FOUND", ""},
+		{"last", "", "110"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("results = %+v, want %d", resp.Results, len(want))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Id != w.id || got.GetError().GetCode() != w.code || got.GetConversion().GetConvertedAmount() != w.converted {
+			t.Errorf("result %d = %+v, want %s with %q%s", i, got, w.id, w.code, w.converted)
+		}
+	}
+
+	// every item invalid still answers per item
+	resp, err = client.ConvertBatch(context.Background(), &converterpb.ConvertBatchRequest{Items: []*converterpb.BatchItem{
+		{Id: "amount", Amount: "", From: "USD", To: "EUR"},
+	}})
+	if err != nil || len(resp.Results) != 1 || resp.Results[0].GetError().GetCode() != "INVALID_AMOUNT" {
+		t.Errorf("batch of one invalid item = %+v, %v", resp, err)
+	}
+	_, err = client.ConvertBatch(context.Background(), &converterpb.ConvertBatchRequest{})
+	if status.Code(err) != codes.InvalidArgument || reason(err) != "INVALID_BATCH" {
+		t.Errorf("empty batch: err = %v, want INVALID_BATCH", err)
+	}
+}
+
+func TestSetRateIsAudited(t *testing.T) {
+	auditLog, err := audit.Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	conv := converter.NewCurrencyConverter()
+	client := dial(t, conv, Config{Audit: auditLog})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "admin-key", "x-request-id", "req-7")
+
+	if _, err := client.SetRate(ctx, &converterpb.SetRateRequest{From: "usd", To: "eur", Rate: "0.9", Reason: "desk update"}); err != nil {
+		t.Fatalf("SetRate: %v", err)
+	}
+	entries := auditLog.Query(audit.Filter{From: "USD", To: "EUR"})
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %+v, want one", entries)
+	}
+	if e := entries[0]; e.Operation != audit.OpSet || e.Principal != "treasury" || e.RequestID != "req-7" ||
+		e.Reason != "desk update" || e.Previous != "0.85" || e.Current != "0.9" {
+		t.Errorf("audit entry = %+v", e)
+	}
+
+	// rejected changes leave no entry
+	for _, req := range []*converterpb.SetRateRequest{
+		{From: "USD", To: "EUR", Rate: "-1"},
+		{From: "USD", To: "EU", Rate: "1"},
+		{From: "USD", To: "EUR", Rate: "one"},
+	} {
+		if _, err := client.SetRate(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SetRate(%+v): err = %v, want InvalidArgument", req, err)
+		}
+	}
+	if entries := auditLog.Query(audit.Filter{}); len(entries) != 1 {
+		t.Errorf("audit entries after rejected changes = %+v", entries)
+	}
+}
+
+func TestWatchRates(t *testing.T) {
+	conv := converter.NewCurrencyConverter()
+	client := dial(t, conv, Config{AnonymousRole: auth.RoleViewer})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchRates(ctx, &converterpb.WatchRatesRequest{Currencies: []string{"jpy"}})
+	if err != nil {
+		t.Fatalf("WatchRates: %v", err)
+	}
+	changes := make(chan *converterpb.RateChange)
+	go func() {
+		for {
+			change, err := stream.Recv()
+			if err != nil {
+				close(changes)
+				return
+			}
+			changes <- change
+		}
+	}()
+
+	// the stream subscribes some time after the call returns, so keep
+	// changing rates until a change arrives; EUR/GBP is filtered out
+	rate := 110
+	for {
+		rate++
+		if err := conv.SetExchangeRate("EUR", "GBP", decimal.NewFromInt(int64(rate))); err != nil {
+			t.Fatalf("SetExchangeRate: %v", err)
+		}
+		if err := conv.SetExchangeRate("USD", "JPY", decimal.NewFromInt(int64(rate))); err != nil {
+			t.Fatalf("SetExchangeRate: %v", err)
+		}
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				t.Fatal("stream ended before any change")
+			}
+			if change.From != "USD" || change.To != "JPY" || change.CurrentRate == "" || change.ChangedAt == nil {
+				t.Errorf("change = %+v, want USD/JPY", change)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no change arrived")
+		}
+	}
+}
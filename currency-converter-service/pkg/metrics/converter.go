@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+)
+
+// Rate update operations, as reported by converter_rate_updates_total
+const (
+	opSet    = "set"
+	opImport = "import"
+	opReset  = "reset"
+	opRemove = "remove"
+)
+
+// instrumentedConverter counts conversions, errors and rate updates of the
+// converter it wraps. Read-only methods pass through unchanged.
+type instrumentedConverter struct {
+	converter.ICurrencyConverter
+	m *Metrics
+}
+
+// Instrument wraps a converter so its conversions, errors and rate updates are
+// recorded, and registers gauges for the age of its stored rates
+func Instrument(conv converter.ICurrencyConverter, m *Metrics) converter.ICurrencyConverter {
+	m.registry.MustRegister(rateAgeCollector{conv: conv})
+	return &instrumentedConverter{ICurrencyConverter: conv, m: m}
+}
+
+// Convert converts an amount and records the outcome
+func (c *instrumentedConverter) Convert(amount decimal.Decimal, from, to string) (converter.Money, error) {
+	money, err := c.ICurrencyConverter.Convert(amount, from, to)
+	c.observeConversion(from, to, err)
+	return money, err
+}
+
+// ConvertAt converts an amount at a past time and records the outcome
+func (c *instrumentedConverter) ConvertAt(amount decimal.Decimal, from, to string, at time.Time) (converter.Money, error) {
+	money, err := c.ICurrencyConverter.ConvertAt(amount, from, to, at)
+	c.observeConversion(from, to, err)
+	return money, err
+}
+
+// ConvertWithResult converts an amount and records the outcome
+func (c *instrumentedConverter) ConvertWithResult(amount decimal.Decimal, from, to string, opts ...converter.ConvertOption) (*converter.ConversionResult, error) {
+	result, err := c.ICurrencyConverter.ConvertWithResult(amount, from, to, opts...)
+	c.observeConversion(from, to, err)
+	return result, err
+}
+
+// ConvertTo solves for a source amount and records the outcome
+func (c *instrumentedConverter) ConvertTo(target decimal.Decimal, from, to string, opts ...converter.ConvertOption) (*converter.ConversionResult, error) {
+	result, err := c.ICurrencyConverter.ConvertTo(target, from, to, opts...)
+	c.observeConversion(from, to, err)
+	return result, err
+}
+
+// ConvertBatch converts a batch and records the outcome of every item
+func (c *instrumentedConverter) ConvertBatch(items []converter.BatchItem, opts ...converter.ConvertOption) ([]converter.BatchResult, error) {
+	results, err := c.ICurrencyConverter.ConvertBatch(items, opts...)
+	if err != nil {
+		c.observeError(err)
+		return results, err
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			c.observeError(*result.Error)
+			continue
+		}
+		c.m.conversions.WithLabelValues(result.Result.FromCurrency, result.Result.ToCurrency).Inc()
+	}
+	return results, nil
+}
+
+// SetExchangeRate stores a rate and records the update
+func (c *instrumentedConverter) SetExchangeRate(from, to string, rate decimal.Decimal) error {
+	err := c.ICurrencyConverter.SetExchangeRate(from, to, rate)
+	c.observeUpdate(opSet, 1, err)
+	return err
+}
+
+// SetExchangeRateAt stores a rate effective at a time and records the entries
+// that changed
+func (c *instrumentedConverter) SetExchangeRateAt(from, to string, rate decimal.Decimal, effective time.Time) ([]converter.RateDiff, error) {
+	diffs, err := c.ICurrencyConverter.SetExchangeRateAt(from, to, rate, effective)
+	c.observeUpdate(opSet, len(diffs), err)
+	return diffs, err
+}
+
+// RemoveCurrency removes a currency and records the rates removed with it
+func (c *instrumentedConverter) RemoveCurrency(code string) ([]converter.RateDiff, error) {
+	diffs, err := c.ICurrencyConverter.RemoveCurrency(code)
+	c.observeUpdate(opRemove, len(diffs), err)
+	return diffs, err
+}
+
+// ResetRates resets rates and records the entries that changed
+func (c *instrumentedConverter) ResetRates(scope converter.ResetScope) ([]converter.RateDiff, error) {
+	diffs, err := c.ICurrencyConverter.ResetRates(scope)
+	c.observeUpdate(opReset, len(diffs), err)
+	return diffs, err
+}
+
+// ImportRates imports rates and records the entries that changed
+func (c *instrumentedConverter) ImportRates(rates converter.RateTable) ([]converter.RateDiff, error) {
+	diffs, err := c.ICurrencyConverter.ImportRates(rates)
+	c.observeUpdate(opImport, len(diffs), err)
+	return diffs, err
+}
+
+// RemoveRatesFrom removes the rates of a source and records the entries removed
+func (c *instrumentedConverter) RemoveRatesFrom(source string) ([]converter.RateDiff, error) {
+	diffs, err := c.ICurrencyConverter.RemoveRatesFrom(source)
+	c.observeUpdate(opRemove, len(diffs), err)
+	return diffs, err
+}
+
+// WithCommit returns a view of the wrapped converter that commits its rate
+// changes through commit and is instrumented like the converter itself
+func (c *instrumentedConverter) WithCommit(commit converter.CommitFunc) converter.ICurrencyConverter {
+	return &instrumentedConverter{ICurrencyConverter: c.ICurrencyConverter.WithCommit(commit), m: c.m}
+}
+
+// observeConversion counts a successful conversion by pair or a failed one by
+// error code. Pairs of failed conversions are not recorded, since they may
+// hold arbitrary client input.
+func (c *instrumentedConverter) observeConversion(from, to string, err error) {
+	if err != nil {
+		c.observeError(err)
+		return
+	}
+	c.m.conversions.WithLabelValues(strings.ToUpper(from), strings.ToUpper(to)).Inc()
+}
+
+// observeUpdate counts changed rate entries or the error that prevented them
+func (c *instrumentedConverter) observeUpdate(operation string, changed int, err error) {
+	if err != nil {
+		c.observeError(err)
+		return
+	}
+	c.m.rateUpdates.WithLabelValues(operation).Add(float64(changed))
+}
+
+// observeError counts an error by its ConversionError code; other errors
+// count as INTERNAL
+func (c *instrumentedConverter) observeError(err error) {
+	code := "INTERNAL"
+	var convErr converter.ConversionError
+	if errors.As(err, &convErr) {
+		code = convErr.Code
+	}
+	c.m.errors.WithLabelValues(code).Inc()
+}
+
+// rateAgeCollector reports the age of every stored rate when scraped
+type rateAgeCollector struct {
+	conv converter.ICurrencyConverter
+}
+
+var rateAgeDesc = prometheus.NewDesc(
+	"converter_rate_age_seconds",
+	"Seconds since each stored rate was last updated.",
+	[]string{"from", "to", "source"}, nil,
+)
+
+func (rateAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rateAgeDesc
+}
+
+// Collect reports rates without an update time as infinitely old, matching
+// how MaxAge treats them
+func (r rateAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, quote := range r.conv.ListRates("") {
+		age := math.Inf(1)
+		if !quote.UpdatedAt.IsZero() {
+			age = now.Sub(quote.UpdatedAt).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(rateAgeDesc, prometheus.GaugeValue, age, quote.From, quote.To, quote.Source)
+	}
+}
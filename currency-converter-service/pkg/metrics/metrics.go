@@ -0,0 +1,78 @@
+// Package metrics exposes Prometheus metrics of the converter service. HTTP
+// traffic is recorded by the API middleware, conversions and rate updates by
+// a decorator around the converter.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the collectors of one service instance and the registry they
+// are exposed from
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	conversions     *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+	rateUpdates     *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them, together with the Go
+// runtime and process collectors, in a registry of their own
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "converter_http_requests_total",
+			Help: "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "converter_http_request_duration_seconds",
+			Help:    "HTTP request latency by route, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		conversions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "converter_conversions_total",
+			Help: "Successful conversions by currency pair.",
+		}, []string{"from", "to"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "converter_errors_total",
+			Help: "Failed converter operations by ConversionError code.",
+		}, []string{"code"}),
+		rateUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "converter_rate_updates_total",
+			Help: "Changed rate entries by the operation that changed them.",
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.conversions,
+		m.errors,
+		m.rateUpdates,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a served HTTP request. route is the route template,
+// such as /rates/{from}/{to}, so paths do not create a series per value.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
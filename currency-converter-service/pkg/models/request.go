@@ -1,15 +1,74 @@
 package models
 
-// ConvertRequest represents a currency conversion request
+import "github.com/shopspring/decimal"
+
+// ConversionOptions are the optional settings shared by the conversion requests.
+// Date converts at historical rates, as "2006-01-02" (end of that day, UTC) or RFC 3339.
+// MaxAge rejects rates older than a duration such as "36h" or a number of seconds.
+// Profile selects a pricing profile and Side its "bid" (default), "ask" or "mid" rate.
+type ConversionOptions struct {
+	Date    string `json:"date,omitempty"`
+	MaxAge  string `json:"maxAge,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	Side    string `json:"side,omitempty" validate:"omitempty,oneof=bid ask mid"`
+}
+
+// ConvertRequest represents a currency conversion request.
+// Amount accepts a string-encoded decimal such as "12.50"; bare JSON numbers are also accepted.
 type ConvertRequest struct {
-	Amount float64 `json:"amount" validate:"required,gt=0"`
-	From   string  `json:"from" validate:"required,len=3"`
-	To     string  `json:"to" validate:"required,len=3"`
+	Amount decimal.Decimal `json:"amount" validate:"required,gt=0"`
+	From   string          `json:"from" validate:"required,len=3,alpha"`
+	To     string          `json:"to" validate:"required,len=3,alpha"`
+	ConversionOptions
 }
 
-// SetRateRequest represents a request to set an exchange rate
+// ReverseConvertRequest asks for the source amount that converts to TargetAmount
+// in the target currency after spread and fees.
+type ReverseConvertRequest struct {
+	TargetAmount decimal.Decimal `json:"targetAmount" validate:"required,gt=0"`
+	From         string          `json:"from" validate:"required,len=3,alpha"`
+	To           string          `json:"to" validate:"required,len=3,alpha"`
+	ConversionOptions
+}
+
+// SetRateRequest represents a request to set an exchange rate.
+// Rate accepts a string-encoded decimal; bare JSON numbers are also accepted.
+// EffectiveAt optionally backdates the rate, in the same formats as ConvertRequest.Date.
+// Reason is recorded in the audit log.
 type SetRateRequest struct {
-	From string  `json:"from" validate:"required,len=3"`
-	To   string  `json:"to" validate:"required,len=3"`
-	Rate float64 `json:"rate" validate:"required,gt=0"`
-}
\ No newline at end of file
+	From        string          `json:"from" validate:"required,len=3,alpha"`
+	To          string          `json:"to" validate:"required,len=3,alpha"`
+	Rate        decimal.Decimal `json:"rate" validate:"required,gt=0"`
+	EffectiveAt string          `json:"effectiveAt,omitempty"`
+	Reason      string          `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// RollbackRequest represents a request to restore the rate an audit entry set
+type RollbackRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// BatchConvertItem is one conversion in a batch request. ID is echoed in the response.
+type BatchConvertItem struct {
+	ID     string          `json:"id"`
+	Amount decimal.Decimal `json:"amount" validate:"required,gt=0"`
+	From   string          `json:"from" validate:"required,len=3,alpha"`
+	To     string          `json:"to" validate:"required,len=3,alpha"`
+}
+
+// BatchConvertRequest represents a batch conversion request.
+// The options apply to every item.
+type BatchConvertRequest struct {
+	Items []BatchConvertItem `json:"items" validate:"required,min=1,max=1000"`
+	ConversionOptions
+}
+
+// AddCurrencyRequest represents a request to register a currency.
+// Fields left out are filled from the ISO 4217 dataset when Code is an ISO code.
+type AddCurrencyRequest struct {
+	Code        string `json:"code" validate:"required,len=3,alpha"`
+	NumericCode string `json:"numericCode,omitempty"`
+	Name        string `json:"name,omitempty"`
+	MinorUnits  *int32 `json:"minorUnits,omitempty" validate:"omitempty,min=0,max=8"`
+	Symbol      string `json:"symbol,omitempty"`
+}
@@ -2,24 +2,216 @@ package models
 
 import "time"
 
-// ConvertResponse represents a currency conversion response
+// ConvertResponse represents a currency conversion response.
+// Amounts and the rate are string-encoded decimals. RateUpdatedAt and
+// RateAgeSeconds are omitted for rates that have not changed since the defaults.
+// ExchangeRate is the applied rate, MidRate the rate before the profile's
+// spread, and NetAmount the converted amount less Fee.
 type ConvertResponse struct {
-	ConvertedAmount float64   `json:"convertedAmount"`
-	OriginalAmount  float64   `json:"originalAmount"`
-	FromCurrency    string    `json:"fromCurrency"`
-	ToCurrency      string    `json:"toCurrency"`
-	ExchangeRate    float64   `json:"exchangeRate"`
-	Timestamp       time.Time `json:"timestamp"`
+	ConvertedAmount string     `json:"convertedAmount"`
+	OriginalAmount  string     `json:"originalAmount"`
+	FromCurrency    string     `json:"fromCurrency"`
+	ToCurrency      string     `json:"toCurrency"`
+	ExchangeRate    string     `json:"exchangeRate"`
+	MidRate         string     `json:"midRate"`
+	Profile         string     `json:"profile,omitempty"`
+	Side            string     `json:"side"`
+	Fee             string     `json:"fee"`
+	NetAmount       string     `json:"netAmount"`
+	RouteKind       string     `json:"routeKind"`
+	Route           []string   `json:"route"`
+	RateSource      string     `json:"rateSource,omitempty"`
+	RateUpdatedAt   *time.Time `json:"rateUpdatedAt,omitempty"`
+	RateAgeSeconds  *int64     `json:"rateAgeSeconds,omitempty"`
+	AsOf            *time.Time `json:"asOf,omitempty"`
+	Timestamp       time.Time  `json:"timestamp"`
+}
+
+// ReverseConvertResponse is the conversion of the source amount found for a
+// target amount. NetAmount is at least TargetAmount; it can exceed it by the
+// rounding of the source currency's minor unit.
+type ReverseConvertResponse struct {
+	TargetAmount string `json:"targetAmount"`
+	ConvertResponse
+}
+
+// RatePointResponse is one entry of a rate time series
+type RatePointResponse struct {
+	Rate        string    `json:"rate,omitempty"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+	Source      string    `json:"source,omitempty"`
+	Removed     bool      `json:"removed,omitempty"`
+}
+
+// RateResponse represents a single exchange rate. UpdatedAt is omitted for
+// rates that have never changed since the service's defaults.
+type RateResponse struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Rate      string     `json:"rate"`
+	Source    string     `json:"source"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	RouteKind string     `json:"routeKind"`
+	Route     []string   `json:"route"`
+}
+
+// RatesResponse represents the rate table, optionally filtered by base currency
+type RatesResponse struct {
+	Base  string         `json:"base,omitempty"`
+	Rates []RateResponse `json:"rates"`
+}
+
+// RateHistoryResponse represents the rate history of a currency pair
+type RateHistoryResponse struct {
+	From   string              `json:"from"`
+	To     string              `json:"to"`
+	Points []RatePointResponse `json:"points"`
+}
+
+// BatchConvertResult holds either the result or the error of one batch item
+type BatchConvertResult struct {
+	ID     string           `json:"id"`
+	Result *ConvertResponse `json:"result,omitempty"`
+	Error  *ErrorResponse   `json:"error,omitempty"`
+}
+
+// BatchConvertResponse represents a batch conversion response, in request order
+type BatchConvertResponse struct {
+	Results []BatchConvertResult `json:"results"`
+}
+
+// CurrencyResponse describes a registered currency. HasRates reports whether
+// any rate is currently stored for it.
+type CurrencyResponse struct {
+	Code        string `json:"code"`
+	NumericCode string `json:"numericCode,omitempty"`
+	Name        string `json:"name"`
+	MinorUnits  int32  `json:"minorUnits"`
+	Symbol      string `json:"symbol,omitempty"`
+	HasRates    bool   `json:"hasRates"`
 }
 
 // CurrenciesResponse represents the supported currencies response
 type CurrenciesResponse struct {
-	Currencies []string `json:"currencies"`
+	Currencies []CurrencyResponse `json:"currencies"`
+}
+
+// RemoveCurrencyResponse represents the result of removing a currency,
+// listing the rates that were removed with it
+type RemoveCurrencyResponse struct {
+	Status  string               `json:"status"`
+	Changes []RateChangeResponse `json:"changes"`
+}
+
+// RateChangeResponse describes a pair changed by a rate mutation. Previous is
+// empty for an added pair and Current is empty for a removed one.
+type RateChangeResponse struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Previous string `json:"previous,omitempty"`
+	Current  string `json:"current,omitempty"`
+}
+
+// RateEventResponse is one message of the rate stream: a change of the
+// current rate of a pair. Previous is empty for an added pair and Current is
+// empty for a removed one; Timestamp is when the change took effect.
+type RateEventResponse struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Previous  string    `json:"previous,omitempty"`
+	Current   string    `json:"current,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
-// ErrorResponse represents an error response
+// ResetRatesResponse represents the result of a rate reset
+type ResetRatesResponse struct {
+	Status  string               `json:"status"`
+	Changes []RateChangeResponse `json:"changes"`
+}
+
+// InverseDeviationResponse describes a pair whose rate and opposite rate are
+// not inverses of each other. All numbers are string-encoded decimals.
+type InverseDeviationResponse struct {
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Rate             string `json:"rate"`
+	InverseRate      string `json:"inverseRate"`
+	Product          string `json:"product"`
+	DeviationPercent string `json:"deviationPercent"`
+}
+
+// InverseAuditResponse lists the pairs deviating more than ThresholdPercent
+type InverseAuditResponse struct {
+	ThresholdPercent string                     `json:"thresholdPercent"`
+	Pairs            []InverseDeviationResponse `json:"pairs"`
+}
+
+// ArbitrageCycleResponse is a round trip through the rate graph, starting and
+// ending with the same currency. Numbers are string-encoded decimals.
+type ArbitrageCycleResponse struct {
+	Currencies       []string `json:"currencies"`
+	Product          string   `json:"product"`
+	DeviationPercent string   `json:"deviationPercent"`
+}
+
+// PairResponse identifies a currency pair
+type PairResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RateHealthResponse represents the result of the rate table integrity check
+type RateHealthResponse struct {
+	Healthy          bool                     `json:"healthy"`
+	Summary          string                   `json:"summary"`
+	CheckedAt        time.Time                `json:"checkedAt"`
+	TolerancePercent string                   `json:"tolerancePercent"`
+	Currencies       []string                 `json:"currencies"`
+	Cycles           []ArbitrageCycleResponse `json:"cycles"`
+	MissingPairs     []PairResponse           `json:"missingPairs"`
+	Unreachable      []PairResponse           `json:"unreachable"`
+}
+
+// AuditEntryResponse is one change of a pair in the audit log. Previous is
+// empty for an added pair and Current is empty for a removed one.
+type AuditEntryResponse struct {
+	ID         int64     `json:"id"`
+	At         time.Time `json:"at"`
+	Operation  string    `json:"operation"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Previous   string    `json:"previous,omitempty"`
+	Current    string    `json:"current,omitempty"`
+	Principal  string    `json:"principal"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	RollbackOf int64     `json:"rollbackOf,omitempty"`
+}
+
+// AuditLogResponse lists audit log entries, oldest first
+type AuditLogResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+}
+
+// RollbackResponse represents the result of a rollback, listing the audit
+// entries it recorded
+type RollbackResponse struct {
+	Status  string               `json:"status"`
+	Entries []AuditEntryResponse `json:"entries"`
+}
+
+// ErrorResponse represents an error response. Details lists the offending
+// fields of a malformed or invalid request body.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    string `json:"code,omitempty"`
-	Details string `json:"details,omitempty"`
-}
\ No newline at end of file
+	Error   string       `json:"error"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes why one request field was rejected. Field is the JSON
+// path of the field, such as "items[2].amount".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
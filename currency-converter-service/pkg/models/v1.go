@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The /v1 API keeps the service's original contract: amounts and rates are
+// JSON numbers and results carry no pricing or rate metadata. New clients
+// should use /v2.
+
+// ConvertRequestV1 represents a /v1 conversion request
+type ConvertRequestV1 struct {
+	Amount decimal.Decimal `json:"amount" validate:"required,gt=0"`
+	From   string          `json:"from" validate:"required,len=3,alpha"`
+	To     string          `json:"to" validate:"required,len=3,alpha"`
+}
+
+// ConvertResponseV1 represents a /v1 conversion response. ConvertedAmount is
+// the amount after fees.
+type ConvertResponseV1 struct {
+	ConvertedAmount float64   `json:"convertedAmount"`
+	OriginalAmount  float64   `json:"originalAmount"`
+	FromCurrency    string    `json:"fromCurrency"`
+	ToCurrency      string    `json:"toCurrency"`
+	ExchangeRate    float64   `json:"exchangeRate"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// SetRateRequestV1 represents a /v1 request to set an exchange rate
+type SetRateRequestV1 struct {
+	From string          `json:"from" validate:"required,len=3,alpha"`
+	To   string          `json:"to" validate:"required,len=3,alpha"`
+	Rate decimal.Decimal `json:"rate" validate:"required,gt=0"`
+}
+
+// CurrenciesResponseV1 lists the codes of the supported currencies
+type CurrenciesResponseV1 struct {
+	Currencies []string `json:"currencies"`
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// FileProvider keeps rates in memory and writes the whole rate history and the
+// currency registry to a JSON or YAML file on every change. The format is
+// chosen from the file extension.
+type FileProvider struct {
+	*converter.MemoryRateProvider
+	path string
+
+	// mu serialises writes of rate and registry changes, each of which
+	// rewrites the file with the last saved state of the other
+	mu         sync.Mutex
+	history    converter.RateHistory
+	currencies []converter.Currency
+}
+
+// rateFile is the on-disk layout; rates are strings to keep decimals exact.
+// Currencies is absent until the registry is first changed.
+type rateFile struct {
+	History    []rateRecord         `json:"history" yaml:"history"`
+	Currencies []converter.Currency `json:"currencies,omitempty" yaml:"currencies,omitempty"`
+}
+
+type rateRecord struct {
+	From        string    `json:"from" yaml:"from"`
+	To          string    `json:"to" yaml:"to"`
+	Rate        string    `json:"rate,omitempty" yaml:"rate,omitempty"`
+	EffectiveAt time.Time `json:"effectiveAt" yaml:"effectiveAt"`
+	Source      string    `json:"source,omitempty" yaml:"source,omitempty"`
+	Removed     bool      `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+// NewFileProvider loads rates from path, creating the file with the default
+// rates if it does not exist yet
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+
+	changes, err := p.load()
+	if errors.Is(err, os.ErrNotExist) {
+		changes = converter.SeedChanges(converter.DefaultRateTable())
+		if err := p.saveHistory(converter.NewRateHistory(changes)); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	p.history = converter.NewRateHistory(changes)
+
+	p.MemoryRateProvider = converter.RestoreMemoryRateProvider(changes, func(history converter.RateHistory, _ []converter.RateChange) error {
+		return p.saveHistory(history)
+	})
+	return p, nil
+}
+
+// SavedCurrencies returns the currency registry stored in the file, or nil
+// if it has never been saved
+func (p *FileProvider) SavedCurrencies() []converter.Currency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currencies
+}
+
+// SaveCurrencies writes the currency registry to the file
+func (p *FileProvider) SaveCurrencies(currencies []converter.Currency) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.save(p.history, currencies); err != nil {
+		return err
+	}
+	p.currencies = currencies
+	return nil
+}
+
+// saveHistory writes a new rate history to the file
+func (p *FileProvider) saveHistory(history converter.RateHistory) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.save(history, p.currencies); err != nil {
+		return err
+	}
+	p.history = history
+	return nil
+}
+
+func (p *FileProvider) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(p.path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func (p *FileProvider) load() ([]converter.RateChange, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	if p.isYAML() {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rate file %s: %v", p.path, err)
+	}
+
+	changes := make([]converter.RateChange, 0, len(file.History))
+	for _, record := range file.History {
+		change := converter.RateChange{
+			Pair: converter.Pair{From: strings.ToUpper(record.From), To: strings.ToUpper(record.To)},
+			Point: converter.RatePoint{
+				EffectiveAt: record.EffectiveAt,
+				Source:      record.Source,
+				Removed:     record.Removed,
+			},
+		}
+		if !record.Removed {
+			rate, err := decimal.NewFromString(record.Rate)
+			if err != nil || rate.Sign() <= 0 {
+				return nil, fmt.Errorf("invalid rate %s/%s %q in %s", record.From, record.To, record.Rate, p.path)
+			}
+			change.Point.Rate = rate
+		}
+		changes = append(changes, change)
+	}
+	p.currencies = file.Currencies
+	return changes, nil
+}
+
+// save writes the history and currencies to a temporary file and renames it
+// over the old one so a crash mid-write never leaves a truncated rate file
+// behind. The caller must hold the lock.
+func (p *FileProvider) save(history converter.RateHistory, currencies []converter.Currency) error {
+	file := rateFile{Currencies: currencies}
+	for _, change := range history.Changes() {
+		record := rateRecord{
+			From:        change.Pair.From,
+			To:          change.Pair.To,
+			EffectiveAt: change.Point.EffectiveAt,
+			Source:      change.Point.Source,
+			Removed:     change.Point.Removed,
+		}
+		if !change.Point.Removed {
+			record.Rate = change.Point.Rate.String()
+		}
+		file.History = append(file.History, record)
+	}
+
+	var data []byte
+	var err error
+	if p.isYAML() {
+		data, err = yaml.Marshal(file)
+	} else {
+		data, err = json.MarshalIndent(file, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write rate file: %v", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to write rate file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"currency-converter-service/pkg/converter"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+)
+
+// SQLiteProvider keeps rates in memory and appends every change to an
+// embedded SQLite database, which also holds the currency registry
+type SQLiteProvider struct {
+	*converter.MemoryRateProvider
+	db *sql.DB
+
+	mu         sync.Mutex
+	currencies []converter.Currency
+}
+
+// NewSQLiteProvider opens the database at path, creating the history table
+// and seeding it with the default rates if it is empty
+func NewSQLiteProvider(path string) (*SQLiteProvider, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &SQLiteProvider{db: db}
+	if err := p.createTables(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	changes, err := p.load()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if p.currencies, err = p.loadCurrencies(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(changes) == 0 {
+		changes = converter.SeedChanges(converter.DefaultRateTable())
+		if err := p.insert(changes); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	p.MemoryRateProvider = converter.RestoreMemoryRateProvider(changes, func(_ converter.RateHistory, changes []converter.RateChange) error {
+		return p.insert(changes)
+	})
+	return p, nil
+}
+
+func (p *SQLiteProvider) createTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS rate_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_currency VARCHAR(3) NOT NULL,
+		to_currency VARCHAR(3) NOT NULL,
+		rate TEXT NOT NULL,
+		effective_at DATETIME NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		removed BOOLEAN NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_rate_history_pair ON rate_history (from_currency, to_currency, effective_at);
+	CREATE TABLE IF NOT EXISTS currencies (
+		code VARCHAR(3) PRIMARY KEY,
+		numeric_code TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		minor_units INTEGER NOT NULL,
+		symbol TEXT NOT NULL DEFAULT ''
+	);`
+
+	_, err := p.db.Exec(query)
+	return err
+}
+
+// Close closes the database
+func (p *SQLiteProvider) Close() error {
+	return p.db.Close()
+}
+
+func (p *SQLiteProvider) load() ([]converter.RateChange, error) {
+	rows, err := p.db.Query(`SELECT from_currency, to_currency, rate, effective_at, source, removed
+			  FROM rate_history ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []converter.RateChange
+	for rows.Next() {
+		var change converter.RateChange
+		var value string
+		err := rows.Scan(&change.Pair.From, &change.Pair.To, &value,
+			&change.Point.EffectiveAt, &change.Point.Source, &change.Point.Removed)
+		if err != nil {
+			return nil, err
+		}
+		if !change.Point.Removed {
+			if change.Point.Rate, err = decimal.NewFromString(value); err != nil {
+				return nil, fmt.Errorf("invalid stored rate %s %q: %v", change.Pair, value, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// insert appends changes to the history in a single transaction
+func (p *SQLiteProvider) insert(changes []converter.RateChange) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, change := range changes {
+		_, err := tx.Exec(`INSERT INTO rate_history (from_currency, to_currency, rate, effective_at, source, removed)
+				  VALUES (?, ?, ?, ?, ?, ?)`,
+			change.Pair.From, change.Pair.To, change.Point.Rate.String(),
+			change.Point.EffectiveAt, change.Point.Source, change.Point.Removed)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SavedCurrencies returns the currency registry stored in the database, or
+// nil if it has never been saved
+func (p *SQLiteProvider) SavedCurrencies() []converter.Currency {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currencies
+}
+
+// SaveCurrencies replaces the stored currency registry in a single transaction
+func (p *SQLiteProvider) SaveCurrencies(currencies []converter.Currency) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM currencies`); err != nil {
+		return err
+	}
+	for _, currency := range currencies {
+		_, err := tx.Exec(`INSERT INTO currencies (code, numeric_code, name, minor_units, symbol) VALUES (?, ?, ?, ?, ?)`,
+			currency.Code, currency.NumericCode, currency.Name, currency.MinorUnits, currency.Symbol)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	p.currencies = currencies
+	return nil
+}
+
+func (p *SQLiteProvider) loadCurrencies() ([]converter.Currency, error) {
+	rows, err := p.db.Query(`SELECT code, numeric_code, name, minor_units, symbol FROM currencies ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []converter.Currency
+	for rows.Next() {
+		var currency converter.Currency
+		if err := rows.Scan(&currency.Code, &currency.NumericCode, &currency.Name, &currency.MinorUnits, &currency.Symbol); err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, currency)
+	}
+	return currencies, rows.Err()
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"currency-converter-service/pkg/converter"
+)
+
+// Kinds of rate store that can be selected at startup
+const (
+	KindMemory = "memory"
+	KindFile   = "file"
+	KindSQLite = "sqlite"
+)
+
+// compile-time checks that the persistent stores keep the currency registry
+var (
+	_ converter.CurrencyStore = (*FileProvider)(nil)
+	_ converter.CurrencyStore = (*SQLiteProvider)(nil)
+)
+
+// Open creates the rate provider of the given kind. File and SQLite stores are
+// seeded with the default rates the first time they are opened.
+func Open(kind, path string) (converter.IExchangeRateProvider, error) {
+	switch strings.ToLower(kind) {
+	case "", KindMemory:
+		return converter.NewMemoryRateProvider(converter.DefaultRateTable()), nil
+	case KindFile:
+		if path == "" {
+			path = "rates.json"
+		}
+		return NewFileProvider(path)
+	case KindSQLite:
+		if path == "" {
+			path = "rates.db"
+		}
+		return NewSQLiteProvider(path)
+	}
+	return nil, fmt.Errorf("unknown rate store %q (want %s, %s or %s)", kind, KindMemory, KindFile, KindSQLite)
+}
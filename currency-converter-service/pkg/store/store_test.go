@@ -0,0 +1,150 @@
+package store
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"currency-converter-service/pkg/converter"
+
+	"github.com/shopspring/decimal"
+)
+
+// storeKinds lists the persistent stores with a file name in a test directory
+func storeKinds(t *testing.T) map[string]string {
+	dir := t.TempDir()
+	return map[string]string{
+		"json":   filepath.Join(dir, "rates.json"),
+		"yaml":   filepath.Join(dir, "rates.yaml"),
+		"sqlite": filepath.Join(dir, "rates.db"),
+	}
+}
+
+func openStore(t *testing.T, name, path string) converter.IExchangeRateProvider {
+	t.Helper()
+	kind := KindFile
+	if name == "sqlite" {
+		kind = KindSQLite
+	}
+	provider, err := Open(kind, path)
+	if err != nil {
+		t.Fatalf("%s: Open: %v", name, err)
+	}
+	return provider
+}
+
+func closeStore(provider converter.IExchangeRateProvider) {
+	if closer, ok := provider.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// TestRoundTrip changes rates in every way the converter can, reopens the
+// store and expects the same current table and history
+func TestRoundTrip(t *testing.T) {
+	now := time.Now()
+	for name, path := range storeKinds(t) {
+		provider := openStore(t, name, path)
+		conv := converter.NewCurrencyConverter(converter.WithRateProvider(provider))
+
+		if err := conv.SetExchangeRate("USD", "EUR", decimal.RequireFromString("0.9")); err != nil {
+			t.Fatalf("%s: SetExchangeRate: %v", name, err)
+		}
+		if err := conv.SetExchangeRate("EUR", "JPY", decimal.RequireFromString("161.123456789012345678")); err != nil {
+			t.Fatalf("%s: SetExchangeRate: %v", name, err)
+		}
+		// behind the rate just set: recorded, but not current
+		if _, err := conv.SetExchangeRateAt("USD", "EUR", decimal.RequireFromString("0.88"), now.Add(-time.Hour)); err != nil {
+			t.Fatalf("%s: SetExchangeRateAt: %v", name, err)
+		}
+		// newer than the default: current from two hours ago
+		if _, err := conv.SetExchangeRateAt("GBP", "USD", decimal.RequireFromString("1.3"), now.Add(-2*time.Hour)); err != nil {
+			t.Fatalf("%s: SetExchangeRateAt: %v", name, err)
+		}
+		if _, err := conv.RemoveCurrency("CAD"); err != nil {
+			t.Fatalf("%s: RemoveCurrency: %v", name, err)
+		}
+		if _, err := conv.ResetRates(converter.ResetScope{From: "EUR", To: "JPY"}); err != nil {
+			t.Fatalf("%s: ResetRates: %v", name, err)
+		}
+
+		table := provider.Snapshot()
+		earlier := provider.SnapshotAt(now.Add(-90 * time.Minute))
+		pairs := converter.DefaultRateTable().Pairs()
+		history := map[converter.Pair][]converter.RatePoint{}
+		for _, pair := range pairs {
+			history[pair] = provider.History(pair.From, pair.To, time.Time{}, time.Time{})
+		}
+		closeStore(provider)
+
+		provider = openStore(t, name, path)
+		if diffs := converter.DiffTables(table, provider.Snapshot()); len(diffs) != 0 {
+			t.Errorf("%s: current table changed on reopening: %+v", name, diffs)
+		}
+		if diffs := converter.DiffTables(earlier, provider.SnapshotAt(now.Add(-90*time.Minute))); len(diffs) != 0 {
+			t.Errorf("%s: table as of 90 minutes ago changed on reopening: %+v", name, diffs)
+		}
+		for _, pair := range pairs {
+			got := provider.History(pair.From, pair.To, time.Time{}, time.Time{})
+			if !samePoints(got, history[pair]) {
+				t.Errorf("%s: history of %s = %+v, want %+v", name, pair, got, history[pair])
+			}
+		}
+		if got := history[converter.Pair{From: "USD", To: "EUR"}]; len(got) != 3 {
+			t.Errorf("%s: USD/EUR history has %d points, want the default, the backdated and the new rate", name, len(got))
+		}
+		if got := history[converter.Pair{From: "USD", To: "CAD"}]; len(got) != 2 || !got[1].Removed {
+			t.Errorf("%s: USD/CAD history = %+v, want the default and its removal", name, got)
+		}
+		closeStore(provider)
+	}
+}
+
+func samePoints(a, b []converter.RatePoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Rate.Equal(b[i].Rate) || !a[i].EffectiveAt.Equal(b[i].EffectiveAt) ||
+			a[i].Source != b[i].Source || a[i].Removed != b[i].Removed {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCurrenciesSurviveReopen(t *testing.T) {
+	for name, path := range storeKinds(t) {
+		provider := openStore(t, name, path)
+		conv := converter.NewCurrencyConverter(converter.WithRateProvider(provider))
+		if err := conv.AddCurrency(converter.Currency{Code: "XAB", Name: "Test unit", MinorUnits: 4}); err != nil {
+			t.Fatalf("%s: AddCurrency: %v", name, err)
+		}
+		if err := conv.SetExchangeRate("USD", "XAB", decimal.RequireFromString("0.5")); err != nil {
+			t.Fatalf("%s: SetExchangeRate: %v", name, err)
+		}
+		if _, err := conv.RemoveCurrency("JPY"); err != nil {
+			t.Fatalf("%s: RemoveCurrency: %v", name, err)
+		}
+		closeStore(provider)
+
+		provider = openStore(t, name, path)
+		conv = converter.NewCurrencyConverter(converter.WithRateProvider(provider))
+		registered := map[string]converter.CurrencyInfo{}
+		for _, info := range conv.ListCurrencies() {
+			registered[info.Code] = info
+		}
+		if _, ok := registered["JPY"]; ok {
+			t.Errorf("%s: removed JPY is registered again", name)
+		}
+		if xab := registered["XAB"]; xab.Name != "Test unit" || xab.MinorUnits != 4 {
+			t.Errorf("%s: XAB after reopening = %+v, want Test unit with 4 minor units", name, xab)
+		}
+		money, err := conv.Convert(decimal.RequireFromString("1.23"), "USD", "XAB")
+		if err != nil || money.AmountString() != "0.6150" {
+			t.Errorf("%s: 1.23 USD = %s, %v; want 0.6150 XAB", name, money, err)
+		}
+		closeStore(provider)
+	}
+}
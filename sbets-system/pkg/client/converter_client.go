@@ -2,14 +2,63 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 )
 
+// RequestIDHeader carries the ID that correlates an SBETS request with the
+// converter calls made for it
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose converter calls carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in a context, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// maxRequestIDLength bounds the length of request IDs accepted from callers,
+// the same bound the converter applies
+const maxRequestIDLength = 128
+
+// ValidRequestID reports whether a caller's request ID is safe to log, echo
+// and forward: 1 to 128 printable ASCII characters without spaces, as the
+// converter accepts them
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID generates a random 128-bit request ID
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
 type ConverterClient struct {
 	baseURL string
+	apiKey  string
 }
 
 type ConvertRequest struct {
@@ -18,6 +67,8 @@ type ConvertRequest struct {
 	To     string  `json:"to"`
 }
 
+// ConvertResponse mirrors the converter service's /v1 response, which keeps
+// amounts and rates as JSON numbers
 type ConvertResponse struct {
 	ConvertedAmount float64 `json:"convertedAmount"`
 	OriginalAmount  float64 `json:"originalAmount"`
@@ -31,10 +82,13 @@ func NewConverterClient() *ConverterClient {
 	if baseURL == "" {
 		baseURL = "http://localhost:8085"
 	}
-	return &ConverterClient{baseURL: baseURL}
+	// CONVERTER_API_KEY is needed when the service does not let anonymous callers convert
+	return &ConverterClient{baseURL: baseURL, apiKey: os.Getenv("CONVERTER_API_KEY")}
 }
 
-func (c *ConverterClient) Convert(amount float64, from, to string) (*ConvertResponse, error) {
+// Convert converts an amount through the converter service, passing on the
+// request ID of ctx
+func (c *ConverterClient) Convert(ctx context.Context, amount float64, from, to string) (*ConvertResponse, error) {
 	req := ConvertRequest{
 		Amount: amount,
 		From:   from,
@@ -46,15 +100,25 @@ func (c *ConverterClient) Convert(amount float64, from, to string) (*ConvertResp
 		return nil, err
 	}
 
-	resp, err := http.Post(c.baseURL+"/convert", "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/convert", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", c.apiKey)
+	}
+	if id := RequestID(ctx); id != "" {
+		httpReq.Header.Set(RequestIDHeader, id)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call converter service: %v", err)
 	}
 	// When Convert() finishes, close the response body.
 	defer resp.Body.Close()
 
-	print("RESPONSE FROM CONVERTER:  ", resp)
-
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("converter service returned status %d", resp.StatusCode)
 	}
@@ -1,6 +1,8 @@
 package expense
 
 import (
+	"context"
+
 	"sbets-system/pkg/client"
 	"sbets-system/pkg/database"
 )
@@ -17,11 +19,11 @@ func NewService(repo *database.Repository, converter *client.ConverterClient) *S
 	}
 }
 
-func (s *Service) AddExpense(amount float64, currency, description string) error {
+func (s *Service) AddExpense(ctx context.Context, amount float64, currency, description string) error {
 	// Convert to USD as base currency
 	convertedAmount := amount
 	if currency != "USD" {
-		result, err := s.converter.Convert(amount, currency, "USD")
+		result, err := s.converter.Convert(ctx, amount, currency, "USD")
 		if err != nil {
 			return err
 		}
@@ -3,10 +3,13 @@ package ui
 import (
 	"encoding/json"
 	"html/template"
+	"log/slog"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
+	"sbets-system/pkg/client"
 	"sbets-system/pkg/expense"
 
 	"github.com/gorilla/mux"
@@ -43,7 +46,7 @@ func (h *Handler) AddExpenseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.expenseService.AddExpense(req.Amount, req.Currency, req.Description); err != nil {
+	if err := h.expenseService.AddExpense(r.Context(), req.Amount, req.Currency, req.Description); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -103,6 +106,7 @@ func SetupRoutes(expenseService *expense.Service) *mux.Router {
 	handler := NewHandler(expenseService)
 
 	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
 
 	// Static files
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static/"))))
@@ -118,3 +122,25 @@ func SetupRoutes(expenseService *expense.Service) *mux.Router {
 
 	return r
 }
+
+// RequestIDMiddleware takes the request ID from the X-Request-ID header, or
+// generates one if the header is missing or unusable, echoes it in the response and stores it in the request
+// context, so converter calls made for the request carry the same ID. Each
+// request is logged with its ID.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(client.RequestIDHeader)
+		if !client.ValidRequestID(id) {
+			id = client.NewRequestID()
+		}
+		w.Header().Set(client.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(client.WithRequestID(r.Context(), id)))
+		slog.Info("request",
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+	})
+}